@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"strings"
+)
+
+// accessibleDescription returns a plain text description of the event, with
+// each detail on its own labelled line, for use with screen readers.
+func accessibleDescription(event *corev2.Event) string {
+	lines := []string{
+		fmt.Sprintf("Status: %s", statusName(event.Check.Status)),
+		fmt.Sprintf("Check: %s", event.Check.Name),
+		fmt.Sprintf("Entity: %s", event.Entity.Name),
+	}
+	if event.Check.Occurrences > 1 {
+		lines = append(lines, fmt.Sprintf("Occurrences: %d", event.Check.Occurrences))
+	}
+	if output := chomp(event.Check.Output); len(output) > 0 {
+		lines = append(lines, fmt.Sprintf("Output: %s", output))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func TestAccessibleMessage(t *testing.T) {
+	assert := assert.New(t)
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+	event.Check.Occurrences = 3
+	event.Check.Output = "disk is full\n"
+	config.slackDescriptionTemplate = defaultTemplate
+	config.accessible = true
+	defer func() { config.accessible = false }()
+
+	attachment := messageAttachment(event)
+	assert.Equal("Status: CRITICAL\nCheck: check1\nEntity: entity1\nOccurrences: 3\nOutput: disk is full", attachment.Text)
+	assert.Empty(attachment.MarkdownIn)
+	assert.False(regexp.MustCompile(`:[a-z_]+:`).MatchString(attachment.Text))
+
+	config.accessible = false
+	assert.Contains(messageAttachment(event).Text, ":repeat:")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+)
+
+// slackAPIURL is the base URL of the Slack Web API, and is replaced in tests
+var slackAPIURL = slack.APIURL
+
+// newAPIClient returns a Slack Web API client authenticated with the bot
+// token, for delivering the event's message.
+func newAPIClient(event *corev2.Event) *slack.Client {
+	return slack.New(config.slackAPIToken,
+		slack.OptionHTTPClient(newHTTPClient(event)),
+		slack.OptionAPIURL(slackAPIURL),
+	)
+}
+
+// postMessage sends the message to its channel with chat.postMessage, as an
+// alternative to posting it to an incoming webhook. It returns the channel ID
+// and timestamp identifying the posted message.
+func postMessage(ctx context.Context, event *corev2.Event, msg *slack.WebhookMessage) (string, string, error) {
+	options := append(messageOptions(msg),
+		slack.MsgOptionUsername(msg.Username),
+		slack.MsgOptionIconURL(msg.IconURL),
+	)
+	if len(msg.ThreadTimestamp) > 0 {
+		options = append(options, slack.MsgOptionTS(msg.ThreadTimestamp))
+	}
+	return newAPIClient(event).PostMessageContext(ctx, msg.Channel, options...)
+}
+
+// updateMessage replaces the content of the message at ts in the message's
+// channel with chat.update. If the message no longer exists, for example
+// because it was deleted in Slack, a new message is posted instead.
+func updateMessage(ctx context.Context, event *corev2.Event, ts string, msg *slack.WebhookMessage) (string, string, error) {
+	updatedChannel, updatedTS, _, err := newAPIClient(event).UpdateMessageContext(ctx, msg.Channel, ts, messageOptions(msg)...)
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) && slackErr.Err == "message_not_found" {
+		logf("Message %s in Slack channel %s no longer exists, posting a new message\n", ts, msg.Channel)
+		return postMessage(ctx, event, msg)
+	}
+	return updatedChannel, updatedTS, err
+}
+
+// messageOptions returns the content of the message as Web API options.
+func messageOptions(msg *slack.WebhookMessage) []slack.MsgOption {
+	options := []slack.MsgOption{
+		slack.MsgOptionText(msg.Text, false),
+		slack.MsgOptionAttachments(msg.Attachments...),
+	}
+	if msg.Blocks != nil {
+		options = append(options, slack.MsgOptionBlocks(msg.Blocks.BlockSet...))
+	}
+	return options
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+)
+
+// Slack rejects header and section blocks with longer text than these
+const (
+	headerTextMaxLength  = 150
+	sectionTextMaxLength = 3000
+)
+
+// messageBlocks returns the message as Block Kit blocks: a header naming the
+// status, check and entity, a section with the templated description, an
+// optional context line naming the executing agent, and the same buttons as
+// the attachment.
+func messageBlocks(event *corev2.Event) []slack.Block {
+	header := fmt.Sprintf("%s: %s on %s", statusName(event.Check.Status), event.Check.Name, event.Entity.Name)
+	textType := slack.MarkdownType
+	if config.accessible {
+		textType = slack.PlainTextType
+	}
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType,
+			truncateBlockText(header, headerTextMaxLength), false, false)),
+		slack.NewSectionBlock(slack.NewTextBlockObject(textType,
+			truncateBlockText(messageDescription(event), sectionTextMaxLength), false, false), nil, nil),
+	}
+	if config.showExecutingAgent {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.PlainTextType,
+			executingAgentContext(event), false, false)))
+	}
+
+	var buttons []slack.BlockElement
+	if event.Check.Status >= config.buttonMinStatus {
+		button := slack.NewButtonBlockElement("view_in_sensu", "", slack.NewTextBlockObject(slack.PlainTextType, "View in Sensu", false, false))
+		button.URL = eventURL(event)
+		buttons = append(buttons, button)
+	}
+	for _, action := range sensuActions(event) {
+		button := slack.NewButtonBlockElement(action.Name, "", slack.NewTextBlockObject(slack.PlainTextType, action.Text, false, false))
+		button.URL = action.URL
+		buttons = append(buttons, button)
+	}
+	for i, action := range annotationLinkActions(event) {
+		button := slack.NewButtonBlockElement(fmt.Sprintf("link_%d", i), "", slack.NewTextBlockObject(slack.PlainTextType, action.Text, false, false))
+		button.URL = action.URL
+		buttons = append(buttons, button)
+	}
+	if len(buttons) > 0 {
+		blocks = append(blocks, slack.NewActionBlock("", buttons...))
+	}
+	return blocks
+}
+
+// truncateBlockText shortens text to fit within a block's length limit,
+// including the ellipsis marking the cut. With --fail-on-oversize the text is
+// left whole, for the oversize check to reject.
+func truncateBlockText(text string, maxLength int) string {
+	if config.failOnOversize || len([]rune(text)) <= maxLength {
+		return text
+	}
+	return truncateFieldValue(text, maxLength-len("..."))
+}
+
+// executingAgentContext describes where the check ran. For a proxy check the
+// event's entity is the agent that executed it, which is named separately
+// from the proxy entity it targeted.
+func executingAgentContext(event *corev2.Event) string {
+	target := event.Check.ProxyEntityName
+	if len(target) == 0 || target == event.Entity.Name {
+		return "Executed by " + event.Entity.Name
+	}
+	return fmt.Sprintf("Executed by %s for proxy entity %s", event.Entity.Name, target)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMessageFormat(t *testing.T) {
+	assert := assert.New(t)
+	var body map[string]interface{}
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiStub.Close()
+
+	config.slackwebHookURL = apiStub.URL
+	config.slackDescriptionTemplate = "*{{ .Check.Name }}* failed"
+	config.sensuUIURL = "https://sensu.example.com"
+	defer func() {
+		config.messageFormat = ""
+		config.sensuUIURL = ""
+	}()
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+
+	config.messageFormat = "attachment"
+	require.NoError(t, sendMessage(event))
+	assert.NotContains(body, "blocks")
+	attachments := body["attachments"].([]interface{})
+	if assert.Len(attachments, 1) {
+		attachment := attachments[0].(map[string]interface{})
+		assert.Equal("*check1* failed", attachment["text"])
+		assert.Len(attachment["actions"], 1)
+	}
+
+	config.messageFormat = "blocks"
+	require.NoError(t, sendMessage(event))
+	assert.NotContains(body, "attachments")
+	assert.Equal("ALERT - entity1/check1:", body["text"])
+	blocks := body["blocks"].([]interface{})
+	require.Len(t, blocks, 3)
+
+	header := blocks[0].(map[string]interface{})
+	assert.Equal("header", header["type"])
+	assert.Equal(map[string]interface{}{"type": "plain_text", "text": "CRITICAL: check1 on entity1"}, header["text"])
+
+	section := blocks[1].(map[string]interface{})
+	assert.Equal("section", section["type"])
+	assert.Equal(map[string]interface{}{"type": "mrkdwn", "text": "*check1* failed"}, section["text"])
+
+	actions := blocks[2].(map[string]interface{})
+	assert.Equal("actions", actions["type"])
+	elements := actions["elements"].([]interface{})
+	if assert.Len(elements, 1) {
+		button := elements[0].(map[string]interface{})
+		assert.Equal("button", button["type"])
+		assert.Equal("View in Sensu", button["text"].(map[string]interface{})["text"])
+		assert.Equal(eventURL(event), button["url"])
+	}
+}
+
+func TestTruncateBlockText(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("short", truncateBlockText("short", 10))
+	assert.Equal("abcdefg...", truncateBlockText("abcdefghijklmnop", 10))
+}
+
+func TestExecutingAgentContext(t *testing.T) {
+	assert := assert.New(t)
+	config.slackDescriptionTemplate = "{{ .Check.Name }}"
+	event := corev2.FixtureEvent("agent1", "check-ping")
+	event.Check.ProxyEntityName = "router1"
+
+	assert.Empty(messageAttachment(event).Footer)
+
+	config.showExecutingAgent = true
+	defer func() {
+		config.showExecutingAgent = false
+		config.handlerFooter = false
+	}()
+	assert.Equal("Executed by agent1 for proxy entity router1", messageAttachment(event).Footer)
+
+	blocks := messageBlocks(event)
+	require.Len(t, blocks, 4)
+	context := blocks[2].(*slack.ContextBlock)
+	if assert.Len(context.ContextElements.Elements, 1) {
+		assert.Equal("Executed by agent1 for proxy entity router1", context.ContextElements.Elements[0].(*slack.TextBlockObject).Text)
+	}
+
+	config.handlerFooter = true
+	assert.Equal("Executed by agent1 for proxy entity router1 | Sent by "+config.PluginConfig.Name, messageAttachment(event).Footer)
+
+	event.Check.ProxyEntityName = ""
+	assert.Equal("Executed by agent1", executingAgentContext(event))
+}
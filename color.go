@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"regexp"
+	"strings"
+)
+
+const (
+	// colorAnnotation is the check or entity annotation that sets a custom
+	// attachment color for an event.
+	colorAnnotation = "slack_color"
+
+	// priorityAnnotation is the check or entity annotation holding the
+	// event's business priority, such as P1.
+	priorityAnnotation = "priority"
+)
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// isHexColor reports whether s is a color in #rrggbb form.
+func isHexColor(s string) bool {
+	return hexColorPattern.MatchString(s)
+}
+
+// annotationColor returns the color set by the event's slack_color
+// annotation, checking the check before the entity. Invalid colors are
+// ignored with a warning.
+func annotationColor(event *corev2.Event) (string, bool) {
+	for _, annotations := range []map[string]string{event.Check.Annotations, event.Entity.Annotations} {
+		color, ok := annotations[colorAnnotation]
+		if !ok {
+			continue
+		}
+		if isHexColor(color) {
+			return color, true
+		}
+		logf("%s: Ignoring invalid %s annotation %q, expected a #rrggbb color\n", config.PluginConfig.Name, colorAnnotation, color)
+	}
+	return "", false
+}
+
+// templateColor returns the color rendered by the color template, if one is
+// configured and renders a #rrggbb color. Empty output falls back to the
+// other colors quietly; invalid output does so with a warning.
+func templateColor(event *corev2.Event) (string, bool) {
+	if len(config.colorTemplate) == 0 {
+		return "", false
+	}
+	color, err := evalTemplate("color", config.colorTemplate, newTemplateContext(event))
+	if err != nil {
+		logf("%s: Error processing color template: %s\n", config.PluginConfig.Name, err)
+		return "", false
+	}
+	color = strings.TrimSpace(color)
+	if len(color) == 0 {
+		return "", false
+	}
+	if !isHexColor(color) {
+		logf("%s: Ignoring invalid color %q from --%s, expected a #rrggbb color\n", config.PluginConfig.Name, color, colorTemplate)
+		return "", false
+	}
+	return color, true
+}
+
+// priorityColor returns the color configured for the event's status and
+// priority annotation, if there is one.
+func priorityColor(event *corev2.Event) (string, bool) {
+	if len(config.priorityColors) == 0 {
+		return "", false
+	}
+	priority := strings.TrimSpace(annotationValue(event, priorityAnnotation))
+	if len(priority) == 0 {
+		return "", false
+	}
+	key := fmt.Sprintf("%d/%s", event.Check.Status, priority)
+	for k, color := range config.priorityColors {
+		if strings.EqualFold(k, key) {
+			return color, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsHexColor(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(isHexColor("#ff8800"))
+	assert.True(isHexColor("#FF8800"))
+	assert.False(isHexColor("ff8800"))
+	assert.False(isHexColor("#f80"))
+	assert.False(isHexColor("red"))
+}
+
+func TestAnnotationColor(t *testing.T) {
+	assert := assert.New(t)
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+
+	event.Check.Annotations = map[string]string{"slack_color": "#123abc"}
+	assert.Equal("#123abc", messageColor(event))
+
+	event.Check.Annotations = map[string]string{"slack_color": "purple"}
+	assert.Equal("#ff0000", messageColor(event))
+
+	// An invalid check color doesn't hide a valid entity color
+	event.Entity.Annotations = map[string]string{"slack_color": "#00ff00"}
+	assert.Equal("#00ff00", messageColor(event))
+}
+
+func TestPriorityColor(t *testing.T) {
+	assert := assert.New(t)
+	config.priorityColors = map[string]string{
+		"1/P1": "#ff0000",
+		"2/P4": "#ff9900",
+	}
+	defer func() { config.priorityColors = nil }()
+
+	warning := corev2.FixtureEvent("entity1", "check1")
+	warning.Check.Status = 1
+	warning.Check.Annotations = map[string]string{"priority": "P1"}
+
+	critical := corev2.FixtureEvent("entity1", "check2")
+	critical.Check.Status = 2
+	critical.Entity.Annotations = map[string]string{"priority": "p4"}
+
+	assert.Equal("#ff0000", messageColor(warning))
+	assert.Equal("#ff9900", messageColor(critical))
+
+	// unmapped combinations use the status color
+	warning.Check.Annotations["priority"] = "P3"
+	assert.Equal("#ffcc00", messageColor(warning))
+	critical.Entity.Annotations = nil
+	assert.Equal("#ff0000", messageColor(critical))
+
+	// an explicit slack_color still wins
+	critical.Check.Annotations = map[string]string{"priority": "P4", "slack_color": "#123456"}
+	assert.Equal("#123456", messageColor(critical))
+}
+
+func TestColorTemplate(t *testing.T) {
+	assert := assert.New(t)
+	config.colorTemplate = `{{ if gt .Check.Occurrences 5 }}#990000{{ else if eq .Check.Status 2 }}not-a-color{{ end }}`
+	defer func() { config.colorTemplate = "" }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+	event.Check.Occurrences = 10
+	assert.Equal("#990000", messageColor(event))
+
+	// invalid and empty output fall back to the status color
+	event.Check.Occurrences = 1
+	assert.Equal("#ff0000", messageColor(event))
+	event.Check.Status = 1
+	assert.Equal("#ffcc00", messageColor(event))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+)
+
+// configFileOptions records the options whose values were loaded from the
+// configuration file.
+var configFileOptions = map[string]bool{}
+
+// loadConfigFile reads option values from a YAML or JSON file mapping option
+// names to values. A value is only applied to an option that isn't set by an
+// annotation, a command line flag or an environment variable, even to its
+// default value, as those take precedence. Unknown option names are ignored
+// with a warning.
+func loadConfigFile(event *corev2.Event, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	known := map[string]bool{}
+	for _, opt := range slackConfigOptions {
+		info, err := describeOption(opt)
+		if err != nil {
+			return err
+		}
+		if len(info.name) == 0 || info.name == configFile || info.name == dumpConfigFlag {
+			continue
+		}
+		known[info.name] = true
+		value, ok := values[info.name]
+		if !ok || optionSet(event, info) {
+			continue
+		}
+		// Options accept the same JSON that their environment variables do
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %v", info.name, err)
+		}
+		if err := opt.SetValue(string(encoded)); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", info.name, err)
+		}
+		configFileOptions[info.name] = true
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			logf("%s: Warning: ignoring unknown option %q in %s\n", config.PluginConfig.Name, name, path)
+		}
+	}
+	return nil
+}
+
+// optionSet reports whether the option is set by a check or entity
+// annotation, a command line flag or an environment variable, whatever its
+// value.
+func optionSet(event *corev2.Event, info optionInfo) bool {
+	if _, source := annotationOption(event, info.keyPath); len(source) > 0 {
+		return true
+	}
+	if flagPassed(os.Args[1:], info) {
+		return true
+	}
+	if len(info.env) > 0 {
+		if v, ok := os.LookupEnv(info.env); ok && len(v) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// flagPassed reports whether the option's flag is among the command line
+// arguments, by its name or its shorthand.
+func flagPassed(args []string, info optionInfo) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == "--"+info.name || strings.HasPrefix(arg, "--"+info.name+"=") {
+			return true
+		}
+		if len(info.shorthand) > 0 && strings.HasPrefix(arg, "-"+info.shorthand) && !strings.HasPrefix(arg, "--") {
+			return true
+		}
+	}
+	return false
+}
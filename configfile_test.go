@@ -0,0 +1,82 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	assert := assert.New(t)
+	saved := config
+	defer func() {
+		config = saved
+		configFileOptions = map[string]bool{}
+	}()
+	config.slackChannel = defaultChannel
+	config.slackUsername = defaultUsername
+	config.slackIconURL = "https://example.com/flag.png"
+	config.includeNamespace = false
+	config.slackFieldValueMaxLength = 0
+	config.subscriptionEmoji = nil
+
+	path := filepath.Join(t.TempDir(), "slack.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+channel: "#from-file"
+username: file-user
+icon-url: https://example.com/file.png
+include-namespace: true
+field-value-max-length: 40
+subscription-emoji-map:
+  database: ":floppy_disk:"
+not-an-option: true
+`), 0600))
+	t.Setenv("SLACK_USERNAME", "env-user")
+	config.slackUsername = "env-user"
+	// A flag set to its default value still takes precedence
+	savedArgs := os.Args
+	os.Args = []string{"sensu-slack-handler", "--icon-url", "https://example.com/flag.png", "--include-namespace=false"}
+	defer func() { os.Args = savedArgs }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	var err error
+	out := captureStdout(t, func() { err = loadConfigFile(event, path) })
+	require.NoError(t, err)
+
+	// file values apply to options left at their defaults
+	assert.Equal("#from-file", config.slackChannel)
+	assert.Equal(40, config.slackFieldValueMaxLength)
+	assert.Equal(map[string]string{"database": ":floppy_disk:"}, config.subscriptionEmoji)
+
+	// environment variables and flags take precedence
+	assert.Equal("env-user", config.slackUsername)
+	assert.Equal("https://example.com/flag.png", config.slackIconURL)
+	assert.False(config.includeNamespace)
+
+	assert.Contains(out, `Warning: ignoring unknown option "not-an-option"`)
+	assert.True(configFileOptions[channel])
+	assert.False(configFileOptions[username])
+	assert.False(configFileOptions[includeNamespace])
+}
+
+func TestLoadConfigFileInvalid(t *testing.T) {
+	saved := config
+	defer func() {
+		config = saved
+		configFileOptions = map[string]bool{}
+	}()
+	config.slackFieldValueMaxLength = 0
+	config.sortFields = defaultSortFields
+
+	path := filepath.Join(t.TempDir(), "slack.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"field-value-max-length": "long"}`), 0600))
+	assert.Error(t, loadConfigFile(nil, path))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"sort-fields": "random"}`), 0600))
+	assert.Error(t, loadConfigFile(nil, path))
+
+	assert.Error(t, loadConfigFile(nil, filepath.Join(t.TempDir(), "missing.yml")))
+}
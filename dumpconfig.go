@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// configEntry describes the effective value of a single option.
+type configEntry struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+	Env    string      `json:"env,omitempty"`
+}
+
+// dumpConfigWithoutEvent is set when --dump-config is run with no event on
+// stdin, in which case the handler is given a placeholder event instead.
+var dumpConfigWithoutEvent bool
+
+// dumpConfigInput returns what the plugin SDK should read as the event for
+// --dump-config, and whether it is a real event. A terminal or empty stdin
+// has no event, so a placeholder event without annotations stands in for it.
+func dumpConfigInput(stdin *os.File) ([]byte, bool, error) {
+	if info, err := stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read stdin: %v", err)
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			return data, true, nil
+		}
+	}
+	data, err := json.Marshal(corev2.FixtureEvent("dump-config", "dump-config"))
+	return data, false, err
+}
+
+// setDumpConfigStdin replaces stdin with the input from dumpConfigInput, so
+// that --dump-config works without an event.
+func setDumpConfigStdin() error {
+	data, hasEvent, err := dumpConfigInput(os.Stdin)
+	if err != nil {
+		return err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	go func() {
+		_, _ = w.Write(data)
+		_ = w.Close()
+	}()
+	os.Stdin = r
+	dumpConfigWithoutEvent = !hasEvent
+	return nil
+}
+
+// dumpConfig returns the effective configuration as indented JSON, with
+// secret values redacted, noting where each value came from.
+func dumpConfig(event *corev2.Event) (string, error) {
+	entries := map[string]configEntry{}
+	for _, opt := range slackConfigOptions {
+		info, err := describeOption(opt)
+		if err != nil {
+			return "", err
+		}
+		if len(info.name) == 0 || info.name == dumpConfigFlag {
+			continue
+		}
+		entry := configEntry{Value: info.value, Source: optionSource(event, info)}
+		if len(info.env) > 0 {
+			entry.Env = info.env
+		}
+		if info.secret && !reflect.DeepEqual(info.value, info.def) {
+			entry.Value = "[REDACTED]"
+		}
+		entries[info.name] = entry
+	}
+	out, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// optionInfo describes a configuration option independent of its type.
+type optionInfo struct {
+	name, env, keyPath, shorthand string
+	value, def                    interface{}
+	secret                        bool
+}
+
+// describeOption returns the details of a configuration option.
+func describeOption(opt sensu.ConfigOption) (optionInfo, error) {
+	switch o := opt.(type) {
+	case *sensu.PluginConfigOption[string]:
+		return optionInfo{o.Argument, o.Env, o.Path, o.Shorthand, *o.Value, o.Default, o.Secret}, nil
+	case *sensu.PluginConfigOption[bool]:
+		return optionInfo{o.Argument, o.Env, o.Path, o.Shorthand, *o.Value, o.Default, o.Secret}, nil
+	case *sensu.PluginConfigOption[int]:
+		return optionInfo{o.Argument, o.Env, o.Path, o.Shorthand, *o.Value, o.Default, o.Secret}, nil
+	case *sensu.PluginConfigOption[uint32]:
+		return optionInfo{o.Argument, o.Env, o.Path, o.Shorthand, *o.Value, o.Default, o.Secret}, nil
+	case *sensu.MapPluginConfigOption[string]:
+		return optionInfo{o.Argument, o.Env, o.Path, o.Shorthand, *o.Value, o.Default, o.Secret}, nil
+	case *sensu.SlicePluginConfigOption[string]:
+		return optionInfo{o.Argument, o.Env, o.Path, o.Shorthand, *o.Value, o.Default, o.Secret}, nil
+	case *sensu.SlicePluginConfigOption[int]:
+		return optionInfo{o.Argument, o.Env, o.Path, o.Shorthand, *o.Value, o.Default, o.Secret}, nil
+	default:
+		return optionInfo{}, fmt.Errorf("unsupported option type %T", opt)
+	}
+}
+
+// annotationOption returns the value of the check or entity annotation that
+// overrides the option at keyPath, looked up the same way as the plugin SDK
+// does, along with which of the two annotations it came from.
+func annotationOption(event *corev2.Event, keyPath string) (string, string) {
+	if event == nil || len(keyPath) == 0 || len(config.PluginConfig.Keyspace) == 0 {
+		return "", ""
+	}
+	key := path.Join(config.PluginConfig.Keyspace, keyPath)
+	for _, k := range []string{strings.ToLower(key), key} {
+		if event.Check != nil && len(event.Check.Annotations[k]) > 0 {
+			return event.Check.Annotations[k], "check annotation"
+		}
+		if event.Entity != nil && len(event.Entity.Annotations[k]) > 0 {
+			return event.Entity.Annotations[k], "entity annotation"
+		}
+	}
+	return "", ""
+}
+
+// optionSource returns where an option's value most likely came from: a
+// check or entity annotation, a command line flag, an environment variable,
+// the configuration file, or the default.
+func optionSource(event *corev2.Event, info optionInfo) string {
+	if _, source := annotationOption(event, info.keyPath); len(source) > 0 {
+		return source
+	}
+	if flagPassed(os.Args[1:], info) {
+		return "flag"
+	}
+	if len(info.env) > 0 {
+		if v, ok := os.LookupEnv(info.env); ok && len(v) > 0 {
+			return "environment"
+		}
+	}
+	if configFileOptions[info.name] {
+		return "config file"
+	}
+	if reflect.DeepEqual(info.value, info.def) {
+		return "default"
+	}
+	return "flag"
+}
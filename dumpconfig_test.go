@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDumpConfig(t *testing.T) {
+	assert := assert.New(t)
+	saved := config
+	defer func() { config = saved }()
+	t.Setenv("SLACK_USERNAME", "alerts-bot")
+	config.slackwebHookURL = "https://hooks.slack.com/services/T000/B000/XXXX"
+	config.channelWebhookURLs = map[string]string{"#ops": "https://hooks.slack.com/services/T000/B001/YYYY"}
+	config.slackChannel = "#ops"
+	config.slackUsername = "alerts-bot"
+	config.slackIconURL = defaultIconURL
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Annotations = map[string]string{"sensu.io/plugins/slack/config/channel": "#ops"}
+
+	out, err := dumpConfig(event)
+	require.NoError(t, err)
+	assert.NotContains(out, "XXXX")
+	assert.NotContains(out, "YYYY")
+
+	var entries map[string]configEntry
+	require.NoError(t, json.Unmarshal([]byte(out), &entries))
+	assert.Equal("[REDACTED]", entries["webhook-url"].Value)
+	assert.Equal("[REDACTED]", entries["channel-webhook-map"].Value)
+	assert.Equal("#ops", entries["channel"].Value)
+	assert.Equal("check annotation", entries["channel"].Source)
+	assert.Equal("alerts-bot", entries["username"].Value)
+	assert.Equal("environment", entries["username"].Source)
+	assert.Equal("SLACK_USERNAME", entries["username"].Env)
+	assert.Equal(defaultIconURL, entries["icon-url"].Value)
+	assert.Equal("default", entries["icon-url"].Source)
+	assert.NotContains(entries, "dump-config")
+}
+
+func TestAnnotationsCantSetHostOptions(t *testing.T) {
+	// Annotations are controlled by agents, so options that run commands or
+	// name files on the backend can't have an annotation path
+	for _, opt := range slackConfigOptions {
+		info, err := describeOption(opt)
+		require.NoError(t, err)
+		switch info.name {
+		case onSuccessCommand, outputFile, stateFile, caFile, configFile, dumpConfigFlag:
+			assert.Empty(t, info.keyPath, info.name)
+		}
+	}
+}
+
+func TestDumpConfigInput(t *testing.T) {
+	assert := assert.New(t)
+	stdin := func(content string) *os.File {
+		path := filepath.Join(t.TempDir(), "stdin")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = f.Close() })
+		return f
+	}
+
+	// An empty stdin gets a valid placeholder event
+	data, hasEvent, err := dumpConfigInput(stdin(""))
+	require.NoError(t, err)
+	assert.False(hasEvent)
+	var event corev2.Event
+	require.NoError(t, json.Unmarshal(data, &event))
+	assert.NoError(event.Validate())
+	assert.Empty(event.Check.Annotations)
+
+	data, hasEvent, err = dumpConfigInput(stdin(`{"check": {"metadata": {"name": "check1"}}}`))
+	require.NoError(t, err)
+	assert.True(hasEvent)
+	assert.Equal(`{"check": {"metadata": {"name": "check1"}}}`, string(data))
+
+	// The placeholder event is never sent
+	saved := config
+	defer func() {
+		config = saved
+		dumpConfigWithoutEvent = false
+	}()
+	dumpConfigWithoutEvent = true
+	config.dumpConfig = false
+	assert.Error(checkArgs(corev2.FixtureEvent("dump-config", "dump-config")))
+}
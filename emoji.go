@@ -0,0 +1,67 @@
+package main
+
+import (
+	_ "embed"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// emojiShortcodeList is the bundled list of standard Slack emoji shortcodes,
+// one per line, without the surrounding colons.
+//
+//go:embed emoji_shortcodes.txt
+var emojiShortcodeList string
+
+var (
+	emojiShortcodes       = strings.Fields(emojiShortcodeList)
+	emojiShortcodePattern = regexp.MustCompile(`:([a-z0-9_+\-]+):`)
+	bareShortcodePattern  = regexp.MustCompile(`^[a-z0-9_+\-]+$`)
+)
+
+// isKnownEmojiShortcode reports whether name, without colons, is a standard
+// Slack emoji shortcode.
+func isKnownEmojiShortcode(name string) bool {
+	i := sort.SearchStrings(emojiShortcodes, name)
+	return i < len(emojiShortcodes) && emojiShortcodes[i] == name
+}
+
+// normalizeEmoji wraps a bare shortcode such as "fire" in colons. Other values
+// are returned with surrounding whitespace removed.
+func normalizeEmoji(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if bareShortcodePattern.MatchString(value) {
+		return ":" + value + ":"
+	}
+	return value
+}
+
+// unknownEmojiShortcodes returns the shortcodes in value that aren't in the
+// bundled list. Custom workspace emoji are reported too, as they can't be
+// told apart from misspellings.
+func unknownEmojiShortcodes(value string) []string {
+	var unknown []string
+	for _, match := range emojiShortcodePattern.FindAllStringSubmatch(value, -1) {
+		if !isKnownEmojiShortcode(match[1]) {
+			unknown = append(unknown, match[0])
+		}
+	}
+	return unknown
+}
+
+// normalizeEmojiOptions normalizes the configured emoji and logs a warning
+// for each shortcode that Slack won't recognise.
+func normalizeEmojiOptions() {
+	keys := make([]string, 0, len(config.subscriptionEmoji))
+	for key := range config.subscriptionEmoji {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := normalizeEmoji(config.subscriptionEmoji[key])
+		config.subscriptionEmoji[key] = value
+		for _, shortcode := range unknownEmojiShortcodes(value) {
+			logf("%s: Warning: unknown emoji shortcode %s in --%s for %q\n", config.PluginConfig.Name, shortcode, subscriptionEmoji, key)
+		}
+	}
+}
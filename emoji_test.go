@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeEmoji(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(":fire:", normalizeEmoji("fire"))
+	assert.Equal(":fire:", normalizeEmoji(" :Fire: "))
+	assert.Equal(":+1::skin-tone-2:", normalizeEmoji(":+1::skin-tone-2:"))
+	assert.Equal("🔥", normalizeEmoji("🔥"))
+}
+
+func TestUnknownEmojiShortcodes(t *testing.T) {
+	assert := assert.New(t)
+	assert.Empty(unknownEmojiShortcodes(":warning:"))
+	assert.Empty(unknownEmojiShortcodes(":+1::skin-tone-2:"))
+	assert.Equal([]string{":warnign:"}, unknownEmojiShortcodes(":fire: :warnign:"))
+}
+
+func TestNormalizeEmojiOptions(t *testing.T) {
+	assert := assert.New(t)
+	config.subscriptionEmoji = map[string]string{"db": "warnign", "web": "globe_with_meridians"}
+	defer func() { config.subscriptionEmoji = nil }()
+
+	out := captureStdout(t, normalizeEmojiOptions)
+	assert.Equal(map[string]string{"db": ":warnign:", "web": ":globe_with_meridians:"}, config.subscriptionEmoji)
+	assert.Contains(out, `Warning: unknown emoji shortcode :warnign: in --subscription-emoji-map for "db"`)
+	assert.NotContains(out, "globe_with_meridians")
+}
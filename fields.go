@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"sort"
+	"strings"
+)
+
+// auditFields returns fields describing who last modified the event's check,
+// when configured to include them. Sensu records only the user who created
+// or last updated a resource, so there is at most one field.
+func auditFields(event *corev2.Event) []slack.AttachmentField {
+	if !config.includeAuditFields || len(event.Check.CreatedBy) == 0 {
+		return nil
+	}
+	return []slack.AttachmentField{
+		{
+			Title: "Check modified by",
+			Value: event.Check.CreatedBy,
+			Short: true,
+		},
+	}
+}
+
+// annotationAttachmentFields returns a short field for each configured
+// annotation present on the check or entity, titled with the annotation key.
+func annotationAttachmentFields(event *corev2.Event) []slack.AttachmentField {
+	var fields []slack.AttachmentField
+	for _, key := range config.annotationFields {
+		value := annotationValue(event, key)
+		if len(value) == 0 {
+			continue
+		}
+		fields = append(fields, slack.AttachmentField{
+			Title: key,
+			Value: value,
+			Short: true,
+		})
+	}
+	return fields
+}
+
+// sortAttachmentFields orders the fields alphabetically by title when
+// configured to, otherwise leaving them in the order they were configured.
+func sortAttachmentFields(fields []slack.AttachmentField) []slack.AttachmentField {
+	if config.sortFields != "alpha" {
+		return fields
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return strings.ToLower(fields[i].Title) < strings.ToLower(fields[j].Title)
+	})
+	return fields
+}
+
+// truncateFieldValues shortens any attachment field value longer than the
+// configured maximum, marking the cut with an ellipsis. It is applied to the
+// final set of fields, regardless of where each field was derived from.
+func truncateFieldValues(fields []slack.AttachmentField) []slack.AttachmentField {
+	if config.slackFieldValueMaxLength <= 0 {
+		return fields
+	}
+	for i, field := range fields {
+		fields[i].Value = truncateFieldValue(field.Value, config.slackFieldValueMaxLength)
+	}
+	return fields
+}
+
+func truncateFieldValue(value string, maxLength int) string {
+	runes := []rune(value)
+	if maxLength <= 0 || len(runes) <= maxLength {
+		return value
+	}
+	return string(runes[0:maxLength]) + "..."
+}
+
+// limitFields moves the configured priority fields to the top and, when there
+// are more fields than the configured maximum, replaces the remainder with a
+// single field counting how many were left out.
+func limitFields(fields []slack.AttachmentField) []slack.AttachmentField {
+	if len(config.priorityFields) > 0 {
+		// Priority fields get negative ranks, in order, so that they sort
+		// ahead of all other fields
+		rank := map[string]int{}
+		for i, title := range config.priorityFields {
+			rank[strings.ToLower(title)] = i - len(config.priorityFields)
+		}
+		sort.SliceStable(fields, func(i, j int) bool {
+			return rank[strings.ToLower(fields[i].Title)] < rank[strings.ToLower(fields[j].Title)]
+		})
+	}
+	if config.maxFields <= 0 || len(fields) <= config.maxFields {
+		return fields
+	}
+	kept := config.maxFields - 1
+	return append(fields[:kept], slack.AttachmentField{
+		Value: fmt.Sprintf("+%d more fields", len(fields)-kept),
+		Short: true,
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTruncateFieldValues(t *testing.T) {
+	assert := assert.New(t)
+	defer func() { config.slackFieldValueMaxLength = 0 }()
+
+	fields := []slack.AttachmentField{
+		{Title: "command", Value: "/opt/sensu/plugins/check-disk-usage --warning 80 --critical 90", Short: true},
+		{Title: "team", Value: "ops", Short: true},
+	}
+
+	config.slackFieldValueMaxLength = 0
+	fields = truncateFieldValues(fields)
+	assert.Equal("/opt/sensu/plugins/check-disk-usage --warning 80 --critical 90", fields[0].Value)
+
+	config.slackFieldValueMaxLength = 18
+	fields = truncateFieldValues(fields)
+	assert.Equal("/opt/sensu/plugins...", fields[0].Value)
+	assert.Equal("ops", fields[1].Value)
+}
+
+func TestSortAttachmentFields(t *testing.T) {
+	assert := assert.New(t)
+	defer func() { config.sortFields = "" }()
+	titles := func(fields []slack.AttachmentField) []string {
+		var t []string
+		for _, f := range fields {
+			t = append(t, f.Title)
+		}
+		return t
+	}
+	fields := func() []slack.AttachmentField {
+		return []slack.AttachmentField{{Title: "team"}, {Title: "Region"}, {Title: "app"}}
+	}
+
+	config.sortFields = "config"
+	assert.Equal([]string{"team", "Region", "app"}, titles(sortAttachmentFields(fields())))
+
+	config.sortFields = "alpha"
+	assert.Equal([]string{"app", "Region", "team"}, titles(sortAttachmentFields(fields())))
+}
+
+func TestLimitFields(t *testing.T) {
+	assert := assert.New(t)
+	defer func() {
+		config.maxFields = 0
+		config.priorityFields = nil
+	}()
+	fields := func() []slack.AttachmentField {
+		return []slack.AttachmentField{
+			{Title: "app", Value: "web"},
+			{Title: "region", Value: "eu"},
+			{Title: "team", Value: "ops"},
+			{Title: "tier", Value: "1"},
+			{Title: "owner", Value: "alice"},
+		}
+	}
+
+	assert.Equal(fields(), limitFields(fields()))
+
+	config.maxFields = 3
+	limited := limitFields(fields())
+	assert.Equal([]slack.AttachmentField{
+		{Title: "app", Value: "web"},
+		{Title: "region", Value: "eu"},
+		{Value: "+3 more fields", Short: true},
+	}, limited)
+
+	config.priorityFields = []string{"Owner", "team"}
+	limited = limitFields(fields())
+	assert.Equal([]slack.AttachmentField{
+		{Title: "owner", Value: "alice"},
+		{Title: "team", Value: "ops"},
+		{Value: "+3 more fields", Short: true},
+	}, limited)
+
+	config.maxFields = 5
+	assert.Len(limitFields(fields()), 5)
+}
+
+func TestAuditFields(t *testing.T) {
+	assert := assert.New(t)
+	config.slackDescriptionTemplate = "{{ .Check.Name }}"
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.CreatedBy = "alice"
+
+	assert.Empty(messageAttachment(event).Fields)
+
+	config.includeAuditFields = true
+	defer func() { config.includeAuditFields = false }()
+	assert.Equal([]slack.AttachmentField{
+		{Title: "Check modified by", Value: "alice", Short: true},
+	}, messageAttachment(event).Fields)
+
+	event.Check.CreatedBy = ""
+	assert.Empty(messageAttachment(event).Fields)
+}
+
+func TestAnnotationAttachmentFields(t *testing.T) {
+	assert := assert.New(t)
+	config.slackDescriptionTemplate = "{{ .Check.Name }}"
+	config.annotationFields = []string{"team", "runbook", "service"}
+	defer func() { config.annotationFields = nil }()
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Annotations = map[string]string{"team": "ops"}
+	event.Entity.Annotations = map[string]string{"service": "checkout"}
+
+	assert.Equal([]slack.AttachmentField{
+		{Title: "team", Value: "ops", Short: true},
+		{Title: "service", Value: "checkout", Short: true},
+	}, messageAttachment(event).Fields)
+}
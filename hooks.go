@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// deliveredMessage identifies a message delivered to Slack. The timestamp and
+// permalink are only known when the message was sent with a bot token.
+type deliveredMessage struct {
+	Channel   string `json:"channel"`
+	TS        string `json:"ts,omitempty"`
+	Permalink string `json:"permalink,omitempty"`
+}
+
+// successCommandInput is written to the on-success command's stdin.
+type successCommandInput struct {
+	Event *corev2.Event    `json:"event"`
+	Slack deliveredMessage `json:"slack"`
+}
+
+// runSuccessCommand runs the configured on-success command through the shell,
+// with the event and the delivered message as JSON on its stdin. The command
+// is killed if it runs for longer than the configured timeout.
+func runSuccessCommand(ctx context.Context, event *corev2.Event, msg deliveredMessage) error {
+	if len(msg.TS) > 0 && len(config.slackAPIToken) > 0 {
+		permalink, err := newAPIClient(event).GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: msg.Channel, Ts: msg.TS})
+		if err != nil {
+			logf("%s: Unable to get a permalink for message %s in Slack channel %s: %v\n", config.PluginConfig.Name, msg.TS, msg.Channel, err)
+		}
+		msg.Permalink = permalink
+	}
+	input, err := json.Marshal(successCommandInput{Event: event, Slack: msg})
+	if err != nil {
+		return err
+	}
+
+	var timeout time.Duration
+	if len(config.onSuccessTimeout) > 0 {
+		if timeout, err = time.ParseDuration(config.onSuccessTimeout); err != nil {
+			return fmt.Errorf("invalid --%s: %v", onSuccessTimeout, err)
+		}
+	}
+	parent := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", config.onSuccessCommand)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", config.onSuccessCommand)
+	}
+	cmd.Stdin = bytes.NewReader(input)
+	// Children of the shell can outlive it when it's killed, so stop waiting
+	// for them to close its output soon after
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// The command is also cut short when --max-runtime runs out first
+		if errors.Is(parent.Err(), context.DeadlineExceeded) {
+			return &maxRuntimeError{action: "running on-success command"}
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		if out := strings.TrimSpace(string(output)); len(out) > 0 {
+			return fmt.Errorf("%v: %s", err, out)
+		}
+		return err
+	}
+	return nil
+}
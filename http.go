@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// headerTransport adds fixed headers to every request it sends.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// newHTTPClient returns the HTTP client used to deliver the event's message
+// to Slack.
+func newHTTPClient(event *corev2.Event) *http.Client {
+	headers := map[string]string{}
+	if len(config.correlationIDHeader) > 0 && len(correlationID) > 0 {
+		headers[config.correlationIDHeader] = correlationID
+	}
+	if len(config.eventHashHeader) > 0 && event.Entity != nil && event.Check != nil {
+		headers[config.eventHashHeader] = eventHash(event)
+	}
+	return &http.Client{
+		Transport: &headerTransport{base: newTransport(), headers: headers},
+	}
+}
+
+// newTransport returns the transport requests are sent with. It uses the
+// configured proxy URL if there is one, otherwise the proxy set by the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables, and verifies
+// server certificates against the configured CA bundle if there is one.
+func newTransport() http.RoundTripper {
+	if len(config.proxyURL) == 0 && len(config.caFile) == 0 && !config.insecureSkipVerify {
+		return http.DefaultTransport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(config.proxyURL) > 0 {
+		proxy, err := url.Parse(config.proxyURL)
+		if err != nil {
+			logf("%s: Ignoring invalid --%s: %s\n", config.PluginConfig.Name, proxyURL, err)
+		} else {
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+	if len(config.caFile) > 0 || config.insecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: config.insecureSkipVerify}
+		if len(config.caFile) > 0 {
+			pool, err := loadCAFile(config.caFile)
+			if err != nil {
+				logf("%s: Ignoring --%s: %s\n", config.PluginConfig.Name, caFile, err)
+			} else {
+				transport.TLSClientConfig.RootCAs = pool
+			}
+		}
+	}
+	return transport
+}
+
+// loadCAFile returns a certificate pool containing the certificates in the
+// PEM bundle at path.
+func loadCAFile(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// eventHash returns a stable hash identifying the event by its namespace,
+// entity, check, status and timestamp, for downstream deduplication.
+func eventHash(event *corev2.Event) string {
+	key := fmt.Sprintf("%s/%s/%d/%d", event.Entity.Namespace, eventKey(event), event.Check.Status, event.Timestamp)
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// postWebhooks posts the message to each webhook URL in turn until one
+// accepts it, so that later URLs act as fallbacks for earlier ones. The
+// errors from every URL are returned if none accept it.
+func postWebhooks(ctx context.Context, urls []string, client *http.Client, msg *slack.WebhookMessage) error {
+	var errs []error
+	for i, webhook := range urls {
+		err := postWebhook(ctx, webhook, client, msg)
+		if err == nil {
+			if len(urls) > 1 {
+				logf("Slack message accepted by webhook %s\n", redactWebhookURL(webhook))
+			}
+			return nil
+		}
+		if i < len(urls)-1 {
+			logf("Slack webhook %s failed: %v, trying the next webhook\n", redactWebhookURL(webhook), err)
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// redactURLError hides the webhook URL that net/http and net/url include in
+// their errors, such as when the webhook can't be reached, since the URL is
+// the webhook's secret.
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redactWebhookURL(urlErr.URL)
+	}
+	return err
+}
+
+// redactWebhookURL returns the webhook URL with everything but its scheme and
+// host hidden, since the path of a Slack webhook URL is its secret.
+func redactWebhookURL(webhook string) string {
+	u, err := url.Parse(webhook)
+	if err != nil || len(u.Host) == 0 {
+		return "[REDACTED]"
+	}
+	return u.Scheme + "://" + u.Host + "/[REDACTED]"
+}
+
+// postWebhook posts the message to the webhook URL. The delivery succeeds
+// when the response status is one of the configured success status codes
+// and, if configured, the response body matches the success body regex.
+// Rate limit and other unsuccessful status responses are returned as the
+// same errors slack-go uses, so that they are retried in the same way.
+func postWebhook(ctx context.Context, webhook string, client *http.Client, msg *slack.WebhookMessage) error {
+	raw, err := marshalMessage(msg)
+	if err != nil {
+		return fmt.Errorf("marshal failed: %w", err)
+	}
+	resp, body, err := postJSON(ctx, webhook, client, raw)
+	if err != nil {
+		return err
+	}
+
+	codes := config.successStatusCodes
+	if len(codes) == 0 {
+		codes = []int{http.StatusOK}
+	}
+	if !slices.Contains(codes, resp.StatusCode) {
+		return statusError(resp)
+	}
+	if len(config.successBodyRegex) > 0 {
+		re, err := regexp.Compile(config.successBodyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %v", successBodyRegex, err)
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("webhook response %q does not match --%s", body, successBodyRegex)
+		}
+	}
+	return nil
+}
+
+// postJSON posts the JSON payload to the webhook URL and returns the
+// response along with its body. The webhook URL is kept out of any error.
+func postJSON(ctx context.Context, webhook string, client *http.Client, payload []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed new request: %w", redactURLError(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to post webhook: %w", redactURLError(err))
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read webhook response: %w", err)
+	}
+	return resp, body, nil
+}
+
+// statusError returns the error for an unsuccessful webhook response: a
+// rate limit error if the webhook asked to retry after a given time, or else
+// a status code error. Both are retried for a 429 response.
+func statusError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return &slack.RateLimitedError{RetryAfter: after}
+		}
+	}
+	return slack.StatusCodeError{Code: resp.StatusCode, Status: resp.Status}
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an
+// HTTP date, and reports whether it could.
+func retryAfter(header string) (time.Duration, bool) {
+	if seconds, err := strconv.ParseInt(header, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now()), 0), true
+}
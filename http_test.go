@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/pem"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEventHashHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	var headers []string
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("X-Event-Hash"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiStub.Close()
+
+	config.slackwebHookURL = apiStub.URL
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	config.eventHashHeader = "X-Event-Hash"
+	defer func() { config.eventHashHeader = "" }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Timestamp = 1700000000
+	assert.NoError(sendMessage(event))
+	assert.NoError(sendMessage(event))
+
+	other := corev2.FixtureEvent("entity1", "check1")
+	other.Timestamp = 1700000000
+	other.Check.Status = 2
+	assert.NoError(sendMessage(other))
+
+	assert.Len(headers, 3)
+	assert.Regexp(`^[0-9a-f]{64}$`, headers[0])
+	assert.Equal(headers[0], headers[1])
+	assert.NotEqual(headers[0], headers[2])
+	assert.Equal(eventHash(event), headers[0])
+}
+
+func TestWebhookSuccessCriteria(t *testing.T) {
+	assert := assert.New(t)
+
+	status, body := http.StatusAccepted, ""
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer apiStub.Close()
+
+	config.slackwebHookURL = apiStub.URL
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	defer func() {
+		config.successStatusCodes = nil
+		config.successBodyRegex = ""
+	}()
+	event := corev2.FixtureEvent("entity1", "check1")
+
+	// 202 is only a success when configured as one
+	assert.Error(sendMessage(event))
+	config.successStatusCodes = []int{http.StatusOK, http.StatusAccepted}
+	assert.NoError(sendMessage(event))
+
+	// A 200 whose body reports an error fails the body check
+	status, body = http.StatusOK, "invalid_payload"
+	config.successBodyRegex = "^ok$"
+	err := sendMessage(event)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "invalid_payload")
+	}
+	body = "ok"
+	assert.NoError(sendMessage(event))
+}
+
+func TestProxyURL(t *testing.T) {
+	assert := assert.New(t)
+
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	config.slackwebHookURL = "http://hooks.slack.invalid/services/T000/B000/XXXX"
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	config.proxyURL = proxy.URL
+	defer func() { config.proxyURL = "" }()
+
+	assert.NoError(sendMessage(corev2.FixtureEvent("entity1", "check1")))
+	assert.Equal([]string{config.slackwebHookURL}, proxied)
+}
+
+func TestCAFile(t *testing.T) {
+	assert := assert.New(t)
+
+	tlsStub := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer tlsStub.Close()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsStub.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, certPEM, 0644))
+
+	config.slackwebHookURL = tlsStub.URL
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	config.maxRetries = 0
+	defer func() {
+		config.caFile = ""
+		config.insecureSkipVerify = false
+	}()
+	event := corev2.FixtureEvent("entity1", "check1")
+
+	// The stub's certificate isn't trusted by the system CAs
+	assert.Error(sendMessage(event))
+	config.caFile = path
+	assert.NoError(sendMessage(event))
+	config.caFile = ""
+	config.insecureSkipVerify = true
+	assert.NoError(sendMessage(event))
+}
+
+func TestFallbackWebhooks(t *testing.T) {
+	assert := assert.New(t)
+	var primaryPosts, secondaryPosts int
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryPosts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryPosts++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer secondary.Close()
+
+	config.slackwebHookURL = primary.URL + "/services/T000/B000/primary," + secondary.URL + "/services/T000/B000/secondary"
+	config.slackDescriptionTemplate = "{{ .Check.Name }}"
+	event := corev2.FixtureEvent("entity1", "check1")
+
+	var err error
+	out := captureStdout(t, func() { err = sendMessage(event) })
+	assert.NoError(err)
+	assert.Equal(1, primaryPosts)
+	assert.Equal(1, secondaryPosts)
+	assert.Contains(out, "Slack message accepted by webhook "+secondary.URL+"/[REDACTED]")
+	assert.NotContains(out, "/services/")
+
+	// Only when every webhook fails does delivery fail
+	config.slackwebHookURL = primary.URL + "," + primary.URL
+	_ = captureStdout(t, func() { err = sendMessage(event) })
+	assert.Error(err)
+	assert.Equal(3, primaryPosts)
+}
+
+func TestUnreachableWebhookRedacted(t *testing.T) {
+	assert := assert.New(t)
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachable.Close()
+	reachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer reachable.Close()
+	defer func() {
+		config.slackwebHookURL = ""
+		config.slackDescriptionTemplate = ""
+	}()
+
+	config.slackwebHookURL = unreachable.URL + "/services/T000/B000/primary," + reachable.URL + "/services/T000/B000/secondary"
+	config.slackDescriptionTemplate = "{{ .Check.Name }}"
+	event := corev2.FixtureEvent("entity1", "check1")
+
+	var err error
+	out := captureStdout(t, func() { err = sendMessage(event) })
+	assert.NoError(err)
+	assert.Contains(out, "Slack webhook "+unreachable.URL+"/[REDACTED] failed")
+	assert.NotContains(out, "/services/")
+
+	// The error returned when every webhook fails doesn't include the URLs either
+	config.slackwebHookURL = unreachable.URL + "/services/T000/B000/primary"
+	out = captureStdout(t, func() { err = sendMessage(event) })
+	assert.Error(err)
+	assert.NotContains(err.Error(), "/services/")
+	assert.NotContains(out, "/services/")
+}
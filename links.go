@@ -0,0 +1,80 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"sort"
+	"strings"
+)
+
+// sensuActions returns the Acknowledge and Resolve buttons for an alert, when
+// configured to show them. Each links to the URL rendered from its template;
+// a button whose template is unset or renders nothing is left out.
+func sensuActions(event *corev2.Event) []slack.AttachmentAction {
+	if !config.showActionButtons || event.Check.Status == 0 {
+		return nil
+	}
+	buttons := []struct {
+		name, text, template string
+	}{
+		{"acknowledge", "Acknowledge", config.ackURLTemplate},
+		{"resolve", "Resolve", config.resolveURLTemplate},
+	}
+	var actions []slack.AttachmentAction
+	for _, button := range buttons {
+		if len(button.template) == 0 {
+			continue
+		}
+		url, err := evalTemplate(button.name, button.template, newTemplateContext(event))
+		if err != nil {
+			logf("%s: Error processing %s URL template: %s\n", config.PluginConfig.Name, button.name, err)
+			continue
+		}
+		if url = strings.TrimSpace(url); len(url) == 0 {
+			continue
+		}
+		actions = append(actions, slack.AttachmentAction{
+			Name: button.name,
+			Text: button.text,
+			Type: "button",
+			URL:  url,
+		})
+	}
+	return actions
+}
+
+// annotationLinkActions returns a button for each configured link annotation
+// present on the check or entity, ordered by annotation name.
+func annotationLinkActions(event *corev2.Event) []slack.AttachmentAction {
+	names := make([]string, 0, len(config.linkAnnotations))
+	for name := range config.linkAnnotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var actions []slack.AttachmentAction
+	for _, name := range names {
+		url := annotationValue(event, name)
+		if len(url) == 0 {
+			continue
+		}
+		actions = append(actions, slack.AttachmentAction{
+			Text: config.linkAnnotations[name],
+			Type: "button",
+			URL:  url,
+		})
+	}
+	return actions
+}
+
+// annotationValue returns the named annotation from the check, or else from
+// the entity, or an empty string if neither has it.
+func annotationValue(event *corev2.Event, name string) string {
+	if value := event.Check.Annotations[name]; len(value) > 0 {
+		return value
+	}
+	if event.Entity != nil {
+		return event.Entity.Annotations[name]
+	}
+	return ""
+}
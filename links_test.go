@@ -0,0 +1,69 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAnnotationLinkActions(t *testing.T) {
+	assert := assert.New(t)
+	config.linkAnnotations = map[string]string{
+		"dashboard_url": "Dashboard",
+		"logs_url":      "Logs",
+		"runbook_url":   "Runbook",
+	}
+	defer func() { config.linkAnnotations = nil }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Annotations = map[string]string{"logs_url": "https://logs.example.com/check1"}
+	event.Entity.Annotations = map[string]string{"dashboard_url": "https://grafana.example.com/entity1"}
+
+	actions := messageAttachment(event).Actions
+	assert.Len(actions, 3)
+	assert.Equal("View in Sensu", actions[0].Text)
+	assert.Equal("Dashboard", actions[1].Text)
+	assert.Equal("https://grafana.example.com/entity1", actions[1].URL)
+	assert.Equal(slack.ActionType("button"), actions[1].Type)
+	assert.Equal("Logs", actions[2].Text)
+	assert.Equal("https://logs.example.com/check1", actions[2].URL)
+}
+
+func TestSensuActions(t *testing.T) {
+	assert := assert.New(t)
+	config.slackDescriptionTemplate = "{{ .Check.Name }}"
+	config.ackURLTemplate = "https://sensu.example.com/ack/{{ .Event.Entity.Name }}/{{ .Event.Check.Name }}"
+	config.resolveURLTemplate = "https://sensu.example.com/resolve?check={{ .Event.Check.Name }}"
+	defer func() {
+		config.showActionButtons = false
+		config.ackURLTemplate = ""
+		config.resolveURLTemplate = ""
+	}()
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+
+	assert.Len(messageAttachment(event).Actions, 1)
+
+	config.showActionButtons = true
+	actions := messageAttachment(event).Actions
+	if assert.Len(actions, 3) {
+		assert.Equal("View in Sensu", actions[0].Text)
+		assert.Equal("Acknowledge", actions[1].Text)
+		assert.Equal("https://sensu.example.com/ack/entity1/check1", actions[1].URL)
+		assert.Equal("Resolve", actions[2].Text)
+		assert.Equal("https://sensu.example.com/resolve?check=check1", actions[2].URL)
+	}
+
+	blocks := messageBlocks(event)
+	elements := blocks[len(blocks)-1].(*slack.ActionBlock).Elements.ElementSet
+	if assert.Len(elements, 3) {
+		assert.Equal("acknowledge", elements[1].(*slack.ButtonBlockElement).ActionID)
+		assert.Equal("https://sensu.example.com/ack/entity1/check1", elements[1].(*slack.ButtonBlockElement).URL)
+		assert.Equal("https://sensu.example.com/resolve?check=check1", elements[2].(*slack.ButtonBlockElement).URL)
+	}
+
+	// Resolved events have nothing left to acknowledge
+	event.Check.Status = 0
+	assert.Len(messageAttachment(event).Actions, 1)
+}
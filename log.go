@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"strings"
+	"time"
+)
+
+// Log levels, as given in JSON log messages
+const (
+	levelInfo  = "info"
+	levelError = "error"
+)
+
+// correlationID identifies the current handler invocation in log messages
+// and, optionally, in requests sent to Slack.
+var correlationID string
+
+// logFields are the structured fields of a JSON log message.
+type logFields map[string]interface{}
+
+// newCorrelationID returns a random identifier for a handler invocation.
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// logf writes a log message, prefixed with the invocation's correlation ID.
+func logf(format string, a ...interface{}) {
+	writeLog(levelInfo, nil, format, a...)
+}
+
+// logEventf writes a log message about the event. In JSON format, the
+// message carries the event's entity, check and status as fields, along with
+// any other fields given.
+func logEventf(level string, event *corev2.Event, fields logFields, format string, a ...interface{}) {
+	eventFields := logFields{}
+	for k, v := range fields {
+		eventFields[k] = v
+	}
+	if event.Entity != nil {
+		eventFields["entity"] = event.Entity.Name
+	}
+	if event.Check != nil {
+		eventFields["check"] = event.Check.Name
+		eventFields["status"] = event.Check.Status
+	}
+	writeLog(level, eventFields, format, a...)
+}
+
+// writeLog writes a log message in the configured format. Text messages are
+// written as they are, and JSON messages as a single object per line.
+func writeLog(level string, fields logFields, format string, a ...interface{}) {
+	if config.logFormat != "json" {
+		if len(correlationID) > 0 {
+			format = "[" + correlationID + "] " + format
+		}
+		fmt.Printf(format, a...)
+		return
+	}
+	entry := logFields{
+		"time":  now().UTC().Format(time.RFC3339),
+		"level": level,
+		"msg":   strings.TrimSpace(fmt.Sprintf(format, a...)),
+	}
+	if len(correlationID) > 0 {
+		entry["correlation_id"] = correlationID
+	}
+	for k, v := range fields {
+		entry[k] = v
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Printf(format, a...)
+		return
+	}
+	fmt.Println(string(line))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCorrelationID(t *testing.T) {
+	assert := assert.New(t)
+	event := corev2.FixtureEvent("entity1", "check1")
+
+	var header string
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Correlation-ID")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiStub.Close()
+
+	config.slackwebHookURL = apiStub.URL
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	config.logRequestBody = true
+	config.correlationIDHeader = "X-Correlation-ID"
+	defer func() {
+		config.logRequestBody = false
+		config.correlationIDHeader = ""
+	}()
+
+	var err error
+	out := captureStdout(t, func() { err = sendMessage(event) })
+	assert.NoError(err)
+
+	prefix := regexp.MustCompile(`^\[([0-9a-f]{16})\] `)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(lines, 2)
+	ids := map[string]bool{}
+	for _, line := range lines {
+		m := prefix.FindStringSubmatch(line)
+		if assert.NotNil(m, line) {
+			ids[m[1]] = true
+		}
+	}
+	assert.Len(ids, 1)
+	assert.True(ids[header])
+
+	// Each invocation gets a new ID
+	first := header
+	_ = captureStdout(t, func() { err = sendMessage(event) })
+	assert.NoError(err)
+	assert.NotEqual(first, header)
+}
+
+func TestJSONLogFormat(t *testing.T) {
+	assert := assert.New(t)
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiStub.Close()
+
+	config.slackwebHookURL = apiStub.URL
+	config.slackChannel = "#alerts"
+	config.slackDescriptionTemplate = "{{ .Check.Name }}"
+	config.logFormat = "json"
+	defer func() {
+		config.slackChannel = ""
+		config.logFormat = ""
+	}()
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+
+	var err error
+	out := captureStdout(t, func() { err = sendMessage(event) })
+	assert.NoError(err)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(out)), &entry))
+	for _, key := range []string{"time", "level", "msg", "correlation_id", "channel", "entity", "check", "status"} {
+		assert.Contains(entry, key)
+	}
+	assert.Equal("info", entry["level"])
+	assert.Equal("Notification sent to Slack channel #alerts", entry["msg"])
+	assert.Equal("#alerts", entry["channel"])
+	assert.Equal("entity1", entry["entity"])
+	assert.Equal("check1", entry["check"])
+	assert.Equal(float64(2), entry["status"])
+
+	// Template errors are logged with the event at error level
+	config.slackDescriptionTemplate = "{{ .Check.Nope }}"
+	out = captureStdout(t, func() { err = sendMessage(event) })
+	assert.NoError(err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal("error", entry["level"])
+	assert.Contains(entry["msg"], "Error processing template")
+	assert.Equal("check1", entry["check"])
+}
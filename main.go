@@ -1,13 +1,24 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/itchyny/gojq"
 	"github.com/sensu/sensu-plugin-sdk/sensu"
-	"github.com/sensu/sensu-plugin-sdk/templates"
 	corev2 "github.com/sensu/core/v2"
 	"github.com/slack-go/slack"
+	"net/http"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 // HandlerConfig contains the Slack handler configuration
@@ -20,25 +31,255 @@ type HandlerConfig struct {
 	slackIconURL             string
 	slackDescriptionTemplate string
 	slackAlertCritical       bool
+	slackAlertWarning        bool
+	slackFieldValueMaxLength int
+	stateFile                string
+	deliverOncePerStatus     bool
+	slackFlapChannel         string
+	flapPercentThreshold     uint32
+	logRequestBody           bool
+	templateProfiles         map[string]string
+	templateProfileLabel     string
+	normalizeChannelNames    bool
+	correlationIDHeader      string
+	slackFallbackChannel     string
+	includeNamespace         bool
+	maintenanceWindow        string
+	channelWebhookURLs       map[string]string
+	accessible               bool
+	maxRuntime               string
+	outputJQ                 string
+	subscriptionEmoji        map[string]string
+	outputFile               string
+	fallbackNewlines         bool
+	dumpConfig               bool
+	sortFields               string
+	emptyOutputText          string
+	handlerFooter            bool
+	normalizeEmojiShortcodes bool
+	skipEmpty                bool
+	templatePreset           string
+	tsFromExecuted           bool
+	linkAnnotations          map[string]string
+	buttonMinStatus          uint32
+	truncateWordBoundary     bool
+	priorityColors           map[string]string
+	eventHashHeader          string
+	configFile               string
+	slackAPIToken            string
+	maxRetries               int
+	retryBackoff             string
+	selfTestOnStart          bool
+	mentionWindow            string
+	retryAfterMax            string
+	outputLanguage           string
+	channelLabel             string
+	maxFields                int
+	priorityFields           []string
+	slackCriticalChannel     string
+	slackWarningChannel      string
+	slackOKChannel           string
+	responseType             string
+	summaryMaxLength         int
+	timestampTimezone        string
+	timestampFormat          string
+	successStatusCodes       []int
+	successBodyRegex         string
+	messageFormat            string
+	templatePartials         map[string]string
+	updateInPlace            bool
+	redactPatterns           []string
+	proxyURL                 string
+	autoLayout               bool
+	autoLayoutThreshold      int
+	caFile                   string
+	insecureSkipVerify       bool
+	dryRun                   bool
+	targets                  []string
+	respectSilencing         bool
+	colorTemplate            string
+	minStatus                uint32
+	includeAuditFields       bool
+	notifyOnTransition       bool
+	repeatOccurrences        int
+	schemaVersion            string
+	annotationFields         []string
+	onSuccessCommand         string
+	onSuccessTimeout         string
+	onSuccessRequired        bool
+	showActionButtons        bool
+	ackURLTemplate           string
+	resolveURLTemplate       string
+	showExecutingAgent       bool
+	criticalMentions         []string
+	failOnOversize           bool
+	mentionFirstOccurrence   bool
+	footerTemplate           string
+	footerIconURL            string
+	threadByLabel            string
+	colorOK                  string
+	colorWarning             string
+	colorCritical            string
+	colorUnknown             string
+	summaryLengthOK          int
+	summaryLengthWarning     int
+	summaryLengthCritical    int
+	summaryLengthUnknown     int
+	retryJitter              string
+	logFormat                string
+	uiEventPathTemplate      string
+	discordWebhookURL        string
+	teamsWebhookURL          string
 }
 
 const (
-	uiURL               = "ui-url"
-	webHookURL          = "webhook-url"
-	channel             = "channel"
-	username            = "username"
-	iconURL             = "icon-url"
-	descriptionTemplate = "description-template"
-	alertCritical       = "alert-on-critical"
+	uiURL                 = "ui-url"
+	webHookURL            = "webhook-url"
+	channel               = "channel"
+	username              = "username"
+	iconURL               = "icon-url"
+	descriptionTemplate   = "description-template"
+	alertCritical         = "alert-on-critical"
+	alertWarning          = "alert-on-warning"
+	fieldValueMaxLength   = "field-value-max-length"
+	stateFile             = "state-file"
+	deliverOnce           = "deliver-once-per-key-per-status"
+	flapChannel           = "flap-percent-channel"
+	flapThreshold         = "flap-percent-threshold"
+	logRequestBody        = "log-request-body"
+	templateProfiles      = "template-profiles"
+	templateProfileKey    = "template-profile-label"
+	channelNormalize      = "channel-case-normalization"
+	correlationHeader     = "correlation-id-header"
+	fallbackChannel       = "fallback-channel"
+	includeNamespace      = "include-namespace"
+	maintenanceWindow     = "maintenance-window"
+	channelWebhookMap     = "channel-webhook-map"
+	accessible            = "accessible"
+	maxRuntime            = "max-runtime"
+	outputJQ              = "output-jq"
+	subscriptionEmoji     = "subscription-emoji-map"
+	outputFile            = "output-file"
+	fallbackNewlines      = "fallback-preserve-newlines"
+	dumpConfigFlag        = "dump-config"
+	sortFields            = "sort-fields"
+	emptyOutputText       = "empty-output-text"
+	handlerFooter         = "handler-footer"
+	emojiNormalize        = "normalize-emoji-shortcodes"
+	skipEmpty             = "skip-empty"
+	templatePreset        = "template-preset"
+	tsFromExecuted        = "attachment-ts-from-executed"
+	linkAnnotations       = "link-annotations"
+	buttonMinStatus       = "button-min-status"
+	wordBoundary          = "truncate-word-boundary"
+	priorityColors        = "priority-colors"
+	eventHashHeader       = "event-hash-header"
+	configFile            = "config-file"
+	apiToken              = "api-token"
+	maxRetries            = "max-retries"
+	retryBackoff          = "retry-backoff"
+	selfTestOnStart       = "self-test-on-start"
+	mentionWindow         = "mention-window"
+	retryAfterMax         = "retry-after-max"
+	outputLanguage        = "output-language"
+	channelLabel          = "channel-label"
+	maxFields             = "max-fields"
+	priorityFields        = "priority-fields"
+	criticalChannel       = "channel-critical"
+	warningChannel        = "channel-warning"
+	okChannel             = "channel-ok"
+	responseType          = "response-type"
+	summaryMaxLength      = "summary-max-length"
+	timestampTimezone     = "timestamp-timezone"
+	timestampFormat       = "timestamp-format"
+	successStatusCodes    = "success-status-codes"
+	successBodyRegex      = "success-body-regex"
+	messageFormat         = "message-format"
+	templatePartials      = "template-partials"
+	updateInPlace         = "update-in-place"
+	redact                = "redact"
+	proxyURL              = "proxy-url"
+	autoLayout            = "auto-layout"
+	autoLayoutThreshold   = "auto-layout-threshold"
+	caFile                = "ca-file"
+	insecureSkipVerify    = "insecure-skip-verify"
+	dryRun                = "dry-run"
+	targets               = "targets"
+	respectSilencing      = "respect-silencing"
+	colorTemplate         = "color-template"
+	minStatus             = "min-status"
+	includeAuditFields    = "include-audit-fields"
+	notifyOnTransition    = "notify-on-transition"
+	repeatOccurrences     = "repeat-interval-occurrences"
+	schemaVersion         = "schema-version"
+	annotationFields      = "fields-from-annotations"
+	onSuccessCommand      = "on-success-command"
+	onSuccessTimeout      = "on-success-timeout"
+	onSuccessRequired     = "on-success-required"
+	showActionButtons     = "show-action-buttons"
+	ackURLTemplate        = "ack-url-template"
+	resolveURLTemplate    = "resolve-url-template"
+	showExecutingAgent    = "show-executing-agent"
+	mentionOnCritical     = "mention-on-critical"
+	failOnOversize        = "fail-on-oversize"
+	mentionFirst          = "mention-first-occurrence-only"
+	footerTemplate        = "footer-template"
+	footerIconURL         = "footer-icon-url"
+	threadByLabel         = "thread-by-label"
+	colorOK               = "color-ok"
+	colorWarning          = "color-warning"
+	colorCritical         = "color-critical"
+	colorUnknown          = "color-unknown"
+	summaryLengthOK       = "summary-max-length-ok"
+	summaryLengthWarning  = "summary-max-length-warning"
+	summaryLengthCritical = "summary-max-length-critical"
+	summaryLengthUnknown  = "summary-max-length-unknown"
+	retryJitter           = "retry-jitter"
+	logFormat             = "log-format"
+	uiEventPathTemplate   = "ui-event-path-template"
+	discordWebhookURL     = "discord-webhook-url"
+	teamsWebhookURL       = "teams-webhook-url"
 
 	defaultChannel       = "#general"
 	defaultIconURL       = "https://www.sensu.io/img/sensu-logo.png"
 	defaultUsername      = "sensu"
 	defaultTemplate      = `{{ if eq .Check.Status 0 }}:white_check_mark:{{ else if eq .Check.Occurrences 1 }}:warning:{{ else }}:repeat:{{ end }} *{{ if eq .Check.Status 0 }}OK{{ else if eq .Check.Status 1 }}WARNING{{ else if eq .Check.Status 2 }}CRITICAL{{ else }}UNKNOWN{{ end }}* *<{{ if index .Check.Annotations "runbook_url" }}{{ .Check.Annotations.runbook_url }}{{ else }}https://sensu.io{{ end }}|{{ .Check.Name }}>* on {{ .Entity.Name }}\n_{{ .Timestamp | UnixTime }}_\n{{ .Check.Output }}`
 	defaultAlert    bool = false
+
+	defaultTemplateProfileLabel = "template_profile"
+	defaultSortFields           = "config"
+	defaultEmptyOutputText      = "(no output)"
+	defaultTemplatePreset       = "standard"
+	defaultMaxRetries           = 3
+	defaultRetryBackoff         = "500ms"
+	defaultRetryAfterMax        = "60s"
+	defaultResponseType         = "in_channel"
+	defaultSummaryMaxLength     = 100
+	defaultMessageFormat        = "attachment"
+	defaultAutoLayoutThreshold  = 200
+	defaultSchemaVersion        = "current"
+	defaultOnSuccessTimeout     = "10s"
+	defaultColorOK              = "#36a64f"
+	defaultColorWarning         = "#ffcc00"
+	defaultColorCritical        = "#ff0000"
+	defaultColorUnknown         = "#6600cc"
+	defaultRetryJitter          = "additive"
+	defaultLogFormat            = "text"
+	defaultUIEventPathTemplate  = "/n/{{ .Entity.Namespace }}/events/{{ .Entity.Name }}/{{ .Check.Name }}"
+
+	// wordBoundaryWindow is how far, in bytes, truncation will back up to
+	// reach a word boundary
+	wordBoundaryWindow = 20
 )
 
 var (
+	// now returns the current time, and is replaced in tests
+	now = time.Now
+
+	// version is the handler's version, set at build time with
+	// -ldflags "-X main.version=..."
+	version string
+
 	config = HandlerConfig{
 		PluginConfig: sensu.PluginConfig{
 			Name:     "sensu-slack-handler",
@@ -62,7 +303,7 @@ var (
 			Argument:  webHookURL,
 			Shorthand: "w",
 			Secret:    true,
-			Usage:     "The webhook url to send messages to",
+			Usage:     "The webhook url to send messages to, or a comma-separated list of webhook urls to try in order until one accepts the message",
 			Value:     &config.slackwebHookURL,
 		},
 		&sensu.PluginConfigOption[string]{
@@ -110,15 +351,762 @@ var (
 			Usage:     "The Slack notification will alert the channel with @channel",
 			Value:     &config.slackAlertCritical,
 		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     alertWarning,
+			Env:      "SLACK_ALERT_ON_WARNING",
+			Argument: alertWarning,
+			Default:  false,
+			Usage:    "The Slack notification will alert the channel with @channel for warning events",
+			Value:    &config.slackAlertWarning,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     fieldValueMaxLength,
+			Env:      "SLACK_FIELD_VALUE_MAX_LENGTH",
+			Argument: fieldValueMaxLength,
+			Default:  0,
+			Usage:    "Truncate attachment field values longer than this many characters (0 disables truncation)",
+			Value:    &config.slackFieldValueMaxLength,
+		},
+		&sensu.PluginConfigOption[string]{
+			Env:      "SLACK_STATE_FILE",
+			Argument: stateFile,
+			Usage:    "Path to a file used to persist notification state between handler runs",
+			Value:    &config.stateFile,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     deliverOnce,
+			Env:      "SLACK_DELIVER_ONCE_PER_KEY_PER_STATUS",
+			Argument: deliverOnce,
+			Default:  false,
+			Usage:    "Only notify once per entity/check and status, tracked in the state file across handler runs",
+			Value:    &config.deliverOncePerStatus,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     flapChannel,
+			Env:      "SLACK_FLAP_PERCENT_CHANNEL",
+			Argument: flapChannel,
+			Usage:    "The channel to post messages to when the check's total state change is at or above the flap threshold",
+			Value:    &config.slackFlapChannel,
+		},
+		&sensu.PluginConfigOption[uint32]{
+			Path:     flapThreshold,
+			Env:      "SLACK_FLAP_PERCENT_THRESHOLD",
+			Argument: flapThreshold,
+			Default:  0,
+			Usage:    "Total state change percentage at which events are routed to the flap channel (0 uses the check's high flap threshold)",
+			Value:    &config.flapPercentThreshold,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     logRequestBody,
+			Env:      "SLACK_LOG_REQUEST_BODY",
+			Argument: logRequestBody,
+			Default:  false,
+			Usage:    "Log the JSON body sent to Slack, with secrets redacted, for troubleshooting",
+			Value:    &config.logRequestBody,
+		},
+		&sensu.MapPluginConfigOption[string]{
+			Path:     templateProfiles,
+			Env:      "SLACK_TEMPLATE_PROFILES",
+			Argument: templateProfiles,
+			Usage:    "Named description templates (name=template, or name=@/path/to/file) selectable per event",
+			Value:    &config.templateProfiles,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     templateProfileKey,
+			Env:      "SLACK_TEMPLATE_PROFILE_LABEL",
+			Argument: templateProfileKey,
+			Default:  defaultTemplateProfileLabel,
+			Usage:    "The check or entity label (or annotation) naming the template profile to use",
+			Value:    &config.templateProfileLabel,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     channelNormalize,
+			Env:      "SLACK_CHANNEL_CASE_NORMALIZATION",
+			Argument: channelNormalize,
+			Default:  false,
+			Usage:    "Normalize channel names to lowercase with a leading # (channel IDs are left unchanged)",
+			Value:    &config.normalizeChannelNames,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     correlationHeader,
+			Env:      "SLACK_CORRELATION_ID_HEADER",
+			Argument: correlationHeader,
+			Usage:    "The name of an HTTP header in which to send the invocation's correlation ID",
+			Value:    &config.correlationIDHeader,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     fallbackChannel,
+			Env:      "SLACK_FALLBACK_CHANNEL",
+			Argument: fallbackChannel,
+			Usage:    "The channel to post messages to when no other channel resolves for an event",
+			Value:    &config.slackFallbackChannel,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     includeNamespace,
+			Env:      "SLACK_INCLUDE_NAMESPACE",
+			Argument: includeNamespace,
+			Default:  false,
+			Usage:    "Prefix the message and its summary with the event's namespace",
+			Value:    &config.includeNamespace,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     maintenanceWindow,
+			Env:      "SLACK_MAINTENANCE_WINDOW",
+			Argument: maintenanceWindow,
+			Usage:    "A maintenance window as RFC 3339 start/end times (start/end), during which messages carry a maintenance banner",
+			Value:    &config.maintenanceWindow,
+		},
+		&sensu.MapPluginConfigOption[string]{
+			Path:     channelWebhookMap,
+			Env:      "SLACK_CHANNEL_WEBHOOK_MAP",
+			Argument: channelWebhookMap,
+			Secret:   true,
+			Usage:    "Webhook urls to use for specific channels (channel=url), falling back to the default webhook url",
+			Value:    &config.channelWebhookURLs,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     accessible,
+			Env:      "SLACK_ACCESSIBLE",
+			Argument: accessible,
+			Default:  false,
+			Usage:    "Send plain, clearly labelled messages without decorative emoji or formatting, for screen readers",
+			Value:    &config.accessible,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     maxRuntime,
+			Env:      "SLACK_MAX_RUNTIME",
+			Argument: maxRuntime,
+			Usage:    "Abort sending if it takes longer than this duration (e.g. 8s); should be less than the handler timeout",
+			Value:    &config.maxRuntime,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     outputJQ,
+			Env:      "SLACK_OUTPUT_JQ",
+			Argument: outputJQ,
+			Usage:    "A jq expression used to extract the relevant part of check output that is JSON",
+			Value:    &config.outputJQ,
+		},
+		&sensu.MapPluginConfigOption[string]{
+			Path:     subscriptionEmoji,
+			Env:      "SLACK_SUBSCRIPTION_EMOJI_MAP",
+			Argument: subscriptionEmoji,
+			Usage:    "Emoji to prefix messages with, by subscription (subscription=:emoji:); the first matching subscription is used",
+			Value:    &config.subscriptionEmoji,
+		},
+		&sensu.PluginConfigOption[string]{
+			Env:      "SLACK_OUTPUT_FILE",
+			Argument: outputFile,
+			Usage:    "Append each message to this file as a line of JSON instead of sending it to Slack",
+			Value:    &config.outputFile,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     fallbackNewlines,
+			Env:      "SLACK_FALLBACK_PRESERVE_NEWLINES",
+			Argument: fallbackNewlines,
+			Default:  false,
+			Usage:    "Keep newlines from multiline check output in the notification fallback text instead of replacing them with spaces",
+			Value:    &config.fallbackNewlines,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Argument: dumpConfigFlag,
+			Default:  false,
+			Usage:    "Print the effective configuration, with secrets redacted, as JSON and exit without sending; without an event on stdin, annotation overrides are not applied",
+			Value:    &config.dumpConfig,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     sortFields,
+			Env:      "SLACK_SORT_FIELDS",
+			Argument: sortFields,
+			Default:  defaultSortFields,
+			Allow:    []string{"config", "alpha"},
+			Usage:    "Order of attachment fields: config (as configured) or alpha (alphabetical by title)",
+			Value:    &config.sortFields,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     emptyOutputText,
+			Env:      "SLACK_EMPTY_OUTPUT_TEXT",
+			Argument: emptyOutputText,
+			Default:  defaultEmptyOutputText,
+			Usage:    "Text used in place of the check output when the check produced no output",
+			Value:    &config.emptyOutputText,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     handlerFooter,
+			Env:      "SLACK_HANDLER_FOOTER",
+			Argument: handlerFooter,
+			Default:  false,
+			Usage:    "Show the handler and pipeline that sent the message in the attachment footer",
+			Value:    &config.handlerFooter,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     emojiNormalize,
+			Env:      "SLACK_NORMALIZE_EMOJI_SHORTCODES",
+			Argument: emojiNormalize,
+			Default:  false,
+			Usage:    "Add missing colons to configured emoji and warn about unknown emoji shortcodes",
+			Value:    &config.normalizeEmojiShortcodes,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     skipEmpty,
+			Env:      "SLACK_SKIP_EMPTY",
+			Argument: skipEmpty,
+			Default:  false,
+			Usage:    "Don't send a message when the description template renders to nothing but whitespace",
+			Value:    &config.skipEmpty,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     templatePreset,
+			Env:      "SLACK_TEMPLATE_PRESET",
+			Argument: templatePreset,
+			Default:  defaultTemplatePreset,
+			Allow:    []string{"minimal", "standard", "verbose"},
+			Usage:    "The built-in description template to use when no description template is given: minimal, standard or verbose",
+			Value:    &config.templatePreset,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     tsFromExecuted,
+			Env:      "SLACK_ATTACHMENT_TS_FROM_EXECUTED",
+			Argument: tsFromExecuted,
+			Default:  false,
+			Usage:    "Timestamp the attachment with when the check was executed, falling back to the event timestamp",
+			Value:    &config.tsFromExecuted,
+		},
+		&sensu.MapPluginConfigOption[string]{
+			Path:     linkAnnotations,
+			Env:      "SLACK_LINK_ANNOTATIONS",
+			Argument: linkAnnotations,
+			Usage:    "Check or entity annotations holding URLs to show as buttons, with the button label (annotation=label)",
+			Value:    &config.linkAnnotations,
+		},
+		&sensu.PluginConfigOption[uint32]{
+			Path:     buttonMinStatus,
+			Env:      "SLACK_BUTTON_MIN_STATUS",
+			Argument: buttonMinStatus,
+			Default:  0,
+			Usage:    "The lowest check status (0 OK, 1 warning, 2 critical) for which the View in Sensu button is shown",
+			Value:    &config.buttonMinStatus,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     wordBoundary,
+			Env:      "SLACK_TRUNCATE_WORD_BOUNDARY",
+			Argument: wordBoundary,
+			Default:  false,
+			Usage:    "When truncating the notification summary, cut at the last word boundary before the limit",
+			Value:    &config.truncateWordBoundary,
+		},
+		&sensu.MapPluginConfigOption[string]{
+			Path:     priorityColors,
+			Env:      "SLACK_PRIORITY_COLORS",
+			Argument: priorityColors,
+			Usage:    "Attachment colors by check status and priority annotation (status/priority=#rrggbb, e.g. 1/P1=#ff0000)",
+			Value:    &config.priorityColors,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     eventHashHeader,
+			Env:      "SLACK_EVENT_HASH_HEADER",
+			Argument: eventHashHeader,
+			Usage:    "The name of an HTTP header in which to send a stable hash of the event, for deduplication by relays",
+			Value:    &config.eventHashHeader,
+		},
+		&sensu.PluginConfigOption[string]{
+			Env:      "SLACK_CONFIG_FILE",
+			Argument: configFile,
+			Usage:    "Path to a YAML or JSON file of option values (option-name: value), used for options not otherwise set",
+			Value:    &config.configFile,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     apiToken,
+			Env:      "SLACK_API_TOKEN",
+			Argument: apiToken,
+			Secret:   true,
+			Usage:    "A Slack bot token to send messages with the Web API instead of a webhook",
+			Value:    &config.slackAPIToken,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     maxRetries,
+			Env:      "SLACK_MAX_RETRIES",
+			Argument: maxRetries,
+			Default:  defaultMaxRetries,
+			Usage:    "How many times to retry sending after a rate limit or server error from Slack",
+			Value:    &config.maxRetries,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     retryBackoff,
+			Env:      "SLACK_RETRY_BACKOFF",
+			Argument: retryBackoff,
+			Default:  defaultRetryBackoff,
+			Usage:    "The delay before the first retry, doubled for each further retry",
+			Value:    &config.retryBackoff,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     selfTestOnStart,
+			Env:      "SLACK_SELF_TEST_ON_START",
+			Argument: selfTestOnStart,
+			Default:  false,
+			Usage:    "Check that Slack accepts the webhook url or API token, and the channel, before handling the event",
+			Value:    &config.selfTestOnStart,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     mentionWindow,
+			Env:      "SLACK_MENTION_WINDOW",
+			Argument: mentionWindow,
+			Usage:    "Only send each mention to a channel once within this duration (e.g. 30m), tracked in the state file",
+			Value:    &config.mentionWindow,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     retryAfterMax,
+			Env:      "SLACK_RETRY_AFTER_MAX",
+			Argument: retryAfterMax,
+			Default:  defaultRetryAfterMax,
+			Usage:    "The longest Retry-After from a Slack rate limit that will be waited for before retrying; longer waits fail instead",
+			Value:    &config.retryAfterMax,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     outputLanguage,
+			Env:      "SLACK_OUTPUT_LANGUAGE",
+			Argument: outputLanguage,
+			Usage:    "Show check output in description templates as a code block with this language hint (e.g. json, yaml)",
+			Value:    &config.outputLanguage,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     channelLabel,
+			Env:      "SLACK_CHANNEL_LABEL",
+			Argument: channelLabel,
+			Usage:    "The check or entity label whose value, when present, is the channel to post the event to (e.g. slack_channel)",
+			Value:    &config.channelLabel,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     maxFields,
+			Env:      "SLACK_MAX_FIELDS",
+			Argument: maxFields,
+			Default:  0,
+			Usage:    "The most attachment fields to show, summarizing the rest in a single field (0 shows all fields)",
+			Value:    &config.maxFields,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     priorityFields,
+			Env:      "SLACK_PRIORITY_FIELDS",
+			Argument: priorityFields,
+			Usage:    "Titles of attachment fields to show first, in order, so that they are kept when fields are limited",
+			Value:    &config.priorityFields,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     criticalChannel,
+			Env:      "SLACK_CHANNEL_CRITICAL",
+			Argument: criticalChannel,
+			Usage:    "The channel to post critical events to instead of the default channel",
+			Value:    &config.slackCriticalChannel,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     warningChannel,
+			Env:      "SLACK_CHANNEL_WARNING",
+			Argument: warningChannel,
+			Usage:    "The channel to post warning events to instead of the default channel",
+			Value:    &config.slackWarningChannel,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     okChannel,
+			Env:      "SLACK_CHANNEL_OK",
+			Argument: okChannel,
+			Usage:    "The channel to post OK (resolved) events to instead of the default channel",
+			Value:    &config.slackOKChannel,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     responseType,
+			Env:      "SLACK_RESPONSE_TYPE",
+			Argument: responseType,
+			Default:  defaultResponseType,
+			Allow:    []string{"in_channel", "ephemeral"},
+			Usage:    "The response_type to include in webhook messages, for receivers such as slash command response URLs that honor it",
+			Value:    &config.responseType,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     summaryMaxLength,
+			Env:      "SLACK_SUMMARY_MAX_LENGTH",
+			Argument: summaryMaxLength,
+			Default:  defaultSummaryMaxLength,
+			Usage:    "Truncate check output in the notification fallback text after this many characters (0 disables truncation)",
+			Value:    &config.summaryMaxLength,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     timestampTimezone,
+			Env:      "SLACK_TIMESTAMP_TIMEZONE",
+			Argument: timestampTimezone,
+			Usage:    "The IANA time zone (e.g. America/New_York) the LocalTime template function formats timestamps in (default UTC)",
+			Value:    &config.timestampTimezone,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     timestampFormat,
+			Env:      "SLACK_TIMESTAMP_FORMAT",
+			Argument: timestampFormat,
+			Default:  defaultTimestampFormat,
+			Usage:    "The Go reference time layout the LocalTime template function formats timestamps with",
+			Value:    &config.timestampFormat,
+		},
+		&sensu.SlicePluginConfigOption[int]{
+			Path:     successStatusCodes,
+			Env:      "SLACK_SUCCESS_STATUS_CODES",
+			Argument: successStatusCodes,
+			Default:  []int{http.StatusOK},
+			Usage:    "HTTP status codes from the webhook that indicate the message was delivered",
+			Value:    &config.successStatusCodes,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     successBodyRegex,
+			Env:      "SLACK_SUCCESS_BODY_REGEX",
+			Argument: successBodyRegex,
+			Usage:    "A regular expression the webhook response body must match for the message to be considered delivered",
+			Value:    &config.successBodyRegex,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     messageFormat,
+			Env:      "SLACK_MESSAGE_FORMAT",
+			Argument: messageFormat,
+			Default:  defaultMessageFormat,
+			Allow:    []string{"attachment", "blocks"},
+			Usage:    "Whether to send the message as a legacy attachment or as Block Kit blocks",
+			Value:    &config.messageFormat,
+		},
+		&sensu.MapPluginConfigOption[string]{
+			Path:     templatePartials,
+			Env:      "SLACK_TEMPLATE_PARTIALS",
+			Argument: templatePartials,
+			Usage:    "Named templates (name=template, or name=@/path/to/file) that description templates can include with {{ template \"name\" . }}",
+			Value:    &config.templatePartials,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     updateInPlace,
+			Env:      "SLACK_UPDATE_IN_PLACE",
+			Argument: updateInPlace,
+			Usage:    "Update the last message sent for an entity/check with chat.update instead of posting a new one (requires --api-token and --state-file)",
+			Value:    &config.updateInPlace,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     redact,
+			Env:      "SLACK_REDACT",
+			Argument: redact,
+			Usage:    "Regular expressions whose matches in check output are replaced with [REDACTED]; checks can add more with the slack_redact_patterns annotation",
+			Value:    &config.redactPatterns,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     proxyURL,
+			Env:      "SLACK_PROXY_URL",
+			Argument: proxyURL,
+			Secret:   true,
+			Usage:    "The URL of an HTTP or HTTPS proxy to send requests to Slack through, instead of the proxy set by the HTTP_PROXY and HTTPS_PROXY environment variables",
+			Value:    &config.proxyURL,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     autoLayout,
+			Env:      "SLACK_AUTO_LAYOUT",
+			Argument: autoLayout,
+			Usage:    "Post descriptions no longer than --auto-layout-threshold as plain message text, and longer ones as attachments",
+			Value:    &config.autoLayout,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     autoLayoutThreshold,
+			Env:      "SLACK_AUTO_LAYOUT_THRESHOLD",
+			Argument: autoLayoutThreshold,
+			Default:  defaultAutoLayoutThreshold,
+			Usage:    "The longest description, in characters, posted as plain text with --auto-layout",
+			Value:    &config.autoLayoutThreshold,
+		},
+		&sensu.PluginConfigOption[string]{
+			Env:      "SLACK_CA_FILE",
+			Argument: caFile,
+			Usage:    "A PEM bundle of CA certificates to verify the Slack or Slack-compatible endpoint's certificate with, instead of the system CAs",
+			Value:    &config.caFile,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     insecureSkipVerify,
+			Env:      "SLACK_INSECURE_SKIP_VERIFY",
+			Argument: insecureSkipVerify,
+			Usage:    "Skip verifying the endpoint's TLS certificate, for testing only",
+			Value:    &config.insecureSkipVerify,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     dryRun,
+			Env:      "SLACK_DRY_RUN",
+			Argument: dryRun,
+			Usage:    "Print the message that would be sent to Slack as JSON instead of sending it",
+			Value:    &config.dryRun,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     targets,
+			Env:      "SLACK_TARGETS",
+			Argument: targets,
+			Usage:    "Where to deliver each message: any of slack, discord (--discord-webhook-url), teams (--teams-webhook-url) and file (--output-file) (default file when --output-file is set, otherwise slack)",
+			Value:    &config.targets,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     discordWebhookURL,
+			Env:      "SLACK_DISCORD_WEBHOOK_URL",
+			Argument: discordWebhookURL,
+			Secret:   true,
+			Usage:    "The Discord webhook url for the discord target, which is sent the Slack message through its Slack-compatible endpoint",
+			Value:    &config.discordWebhookURL,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     teamsWebhookURL,
+			Env:      "SLACK_TEAMS_WEBHOOK_URL",
+			Argument: teamsWebhookURL,
+			Secret:   true,
+			Usage:    "The Microsoft Teams webhook url for the teams target, which is sent the message as a message card",
+			Value:    &config.teamsWebhookURL,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     respectSilencing,
+			Env:      "SLACK_RESPECT_SILENCING",
+			Argument: respectSilencing,
+			Default:  true,
+			Usage:    "Skip notifications for silenced events (--respect-silencing=false to notify anyway)",
+			Value:    &config.respectSilencing,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     colorTemplate,
+			Env:      "SLACK_COLOR_TEMPLATE",
+			Argument: colorTemplate,
+			Usage:    "A template rendering the attachment color as #rrggbb, taking precedence over all other colors when it renders one",
+			Value:    &config.colorTemplate,
+		},
+		&sensu.PluginConfigOption[uint32]{
+			Path:     minStatus,
+			Env:      "SLACK_MIN_STATUS",
+			Argument: minStatus,
+			Usage:    "Only notify for statuses of at least this severity (0-3, with unknown statuses the most severe), and for resolutions of checks that were at least this severe",
+			Value:    &config.minStatus,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     includeAuditFields,
+			Env:      "SLACK_INCLUDE_AUDIT_FIELDS",
+			Argument: includeAuditFields,
+			Usage:    "Add a field naming the user who last created or updated the check, when Sensu has recorded one",
+			Value:    &config.includeAuditFields,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     notifyOnTransition,
+			Env:      "SLACK_NOTIFY_ON_TRANSITION",
+			Argument: notifyOnTransition,
+			Usage:    "Only notify when the check's status differs from its previous status in the check history",
+			Value:    &config.notifyOnTransition,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     repeatOccurrences,
+			Env:      "SLACK_REPEAT_INTERVAL_OCCURRENCES",
+			Argument: repeatOccurrences,
+			Usage:    "After the first alert, only repeat it when the check's occurrences are a multiple of this number (0 repeats every occurrence)",
+			Value:    &config.repeatOccurrences,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     schemaVersion,
+			Env:      "SLACK_SCHEMA_VERSION",
+			Argument: schemaVersion,
+			Default:  defaultSchemaVersion,
+			Allow:    []string{"legacy", "current"},
+			Usage:    "The message schema to emit; legacy omits newer fields (blocks, mrkdwn_in) for older Slack-compatible receivers",
+			Value:    &config.schemaVersion,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     annotationFields,
+			Env:      "SLACK_FIELDS_FROM_ANNOTATIONS",
+			Argument: annotationFields,
+			Usage:    "Annotation keys to show as short attachment fields, taken from the check or else the entity; missing keys are skipped",
+			Value:    &config.annotationFields,
+		},
+		&sensu.PluginConfigOption[string]{
+			Env:      "SLACK_ON_SUCCESS_COMMAND",
+			Argument: onSuccessCommand,
+			Usage:    "A shell command to run after each message is delivered to Slack, given the event and the message's channel, ts and permalink as JSON on stdin",
+			Value:    &config.onSuccessCommand,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     onSuccessTimeout,
+			Env:      "SLACK_ON_SUCCESS_TIMEOUT",
+			Argument: onSuccessTimeout,
+			Default:  defaultOnSuccessTimeout,
+			Usage:    "Kill the on-success command if it runs for longer than this duration (0 for no limit)",
+			Value:    &config.onSuccessTimeout,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     onSuccessRequired,
+			Env:      "SLACK_ON_SUCCESS_REQUIRED",
+			Argument: onSuccessRequired,
+			Usage:    "Fail the handler if the on-success command fails, rather than logging the failure",
+			Value:    &config.onSuccessRequired,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     showActionButtons,
+			Env:      "SLACK_SHOW_ACTION_BUTTONS",
+			Argument: showActionButtons,
+			Usage:    "Show Acknowledge and Resolve buttons on alerts, linking to the URLs rendered from --ack-url-template and --resolve-url-template",
+			Value:    &config.showActionButtons,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     ackURLTemplate,
+			Env:      "SLACK_ACK_URL_TEMPLATE",
+			Argument: ackURLTemplate,
+			Usage:    "A template for the Acknowledge button's URL, rendered with the event",
+			Value:    &config.ackURLTemplate,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     resolveURLTemplate,
+			Env:      "SLACK_RESOLVE_URL_TEMPLATE",
+			Argument: resolveURLTemplate,
+			Usage:    "A template for the Resolve button's URL, rendered with the event",
+			Value:    &config.resolveURLTemplate,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     showExecutingAgent,
+			Env:      "SLACK_SHOW_EXECUTING_AGENT",
+			Argument: showExecutingAgent,
+			Usage:    "Add a context line naming the agent that executed the check and, for proxy checks, the proxy entity it targeted",
+			Value:    &config.showExecutingAgent,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     mentionOnCritical,
+			Env:      "SLACK_MENTION_ON_CRITICAL",
+			Argument: mentionOnCritical,
+			Usage:    "Slack users or user groups to mention on critical events, as IDs or in mention syntax (e.g. U0123ABCD,<!subteam^S0456EFGH>)",
+			Value:    &config.criticalMentions,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     failOnOversize,
+			Env:      "SLACK_FAIL_ON_OVERSIZE",
+			Argument: failOnOversize,
+			Usage:    "Fail the handler when the message exceeds Slack's length limits, rather than truncating it",
+			Value:    &config.failOnOversize,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     mentionFirst,
+			Env:      "SLACK_MENTION_FIRST_OCCURRENCE_ONLY",
+			Argument: mentionFirst,
+			Usage:    "Only mention the channel, users or groups on the first occurrence of a status, not on every repeat",
+			Value:    &config.mentionFirstOccurrence,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     footerTemplate,
+			Env:      "SLACK_FOOTER_TEMPLATE",
+			Argument: footerTemplate,
+			Usage:    "A template for the attachment footer, in Golang text/template format; .Version is the handler's version",
+			Value:    &config.footerTemplate,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     footerIconURL,
+			Env:      "SLACK_FOOTER_ICON_URL",
+			Argument: footerIconURL,
+			Usage:    "A URL to a small icon shown beside the attachment footer",
+			Value:    &config.footerIconURL,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     threadByLabel,
+			Env:      "SLACK_THREAD_BY_LABEL",
+			Argument: threadByLabel,
+			Usage:    "A check or entity label whose value threads related events together, across entities and namespaces (requires --api-token and --state-file)",
+			Value:    &config.threadByLabel,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     colorOK,
+			Env:      "SLACK_COLOR_OK",
+			Argument: colorOK,
+			Default:  defaultColorOK,
+			Usage:    "The attachment color for OK events, as a #rrggbb color",
+			Value:    &config.colorOK,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     colorWarning,
+			Env:      "SLACK_COLOR_WARNING",
+			Argument: colorWarning,
+			Default:  defaultColorWarning,
+			Usage:    "The attachment color for warning events, as a #rrggbb color",
+			Value:    &config.colorWarning,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     colorCritical,
+			Env:      "SLACK_COLOR_CRITICAL",
+			Argument: colorCritical,
+			Default:  defaultColorCritical,
+			Usage:    "The attachment color for critical events, as a #rrggbb color",
+			Value:    &config.colorCritical,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     colorUnknown,
+			Env:      "SLACK_COLOR_UNKNOWN",
+			Argument: colorUnknown,
+			Default:  defaultColorUnknown,
+			Usage:    "The attachment color for unknown events, as a #rrggbb color",
+			Value:    &config.colorUnknown,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     summaryLengthOK,
+			Env:      "SLACK_SUMMARY_MAX_LENGTH_OK",
+			Argument: summaryLengthOK,
+			Usage:    "Truncate check output in the fallback text of OK events after this many characters (0 uses --summary-max-length, -1 disables truncation)",
+			Value:    &config.summaryLengthOK,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     summaryLengthWarning,
+			Env:      "SLACK_SUMMARY_MAX_LENGTH_WARNING",
+			Argument: summaryLengthWarning,
+			Usage:    "Truncate check output in the fallback text of warning events after this many characters (0 uses --summary-max-length, -1 disables truncation)",
+			Value:    &config.summaryLengthWarning,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     summaryLengthCritical,
+			Env:      "SLACK_SUMMARY_MAX_LENGTH_CRITICAL",
+			Argument: summaryLengthCritical,
+			Usage:    "Truncate check output in the fallback text of critical events after this many characters (0 uses --summary-max-length, -1 disables truncation)",
+			Value:    &config.summaryLengthCritical,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     summaryLengthUnknown,
+			Env:      "SLACK_SUMMARY_MAX_LENGTH_UNKNOWN",
+			Argument: summaryLengthUnknown,
+			Usage:    "Truncate check output in the fallback text of unknown events after this many characters (0 uses --summary-max-length, -1 disables truncation)",
+			Value:    &config.summaryLengthUnknown,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     retryJitter,
+			Env:      "SLACK_RETRY_JITTER",
+			Argument: retryJitter,
+			Default:  defaultRetryJitter,
+			Allow:    []string{"additive", "full", "equal", "none"},
+			Usage:    "How retry delays are randomized: additive adds up to 50%, full picks up to the whole delay, equal keeps half and picks the rest, none disables jitter",
+			Value:    &config.retryJitter,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     logFormat,
+			Env:      "SLACK_LOG_FORMAT",
+			Argument: logFormat,
+			Default:  defaultLogFormat,
+			Allow:    []string{"text", "json"},
+			Usage:    "The format of log messages: text, or json with one object per line for log pipelines",
+			Value:    &config.logFormat,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     uiEventPathTemplate,
+			Env:      "SLACK_UI_EVENT_PATH_TEMPLATE",
+			Argument: uiEventPathTemplate,
+			Default:  defaultUIEventPathTemplate,
+			Usage:    "A template for the event's path in the Sensu UI, appended to --ui-url for the View in Sensu button",
+			Value:    &config.uiEventPathTemplate,
+		},
 	}
 )
 
 func main() {
+	// The configuration can be dumped without an event, which the plugin SDK
+	// otherwise requires
+	if flagPassed(os.Args[1:], optionInfo{name: dumpConfigFlag}) {
+		if err := setDumpConfigStdin(); err != nil {
+			logf("%s: %v\n", config.PluginConfig.Name, err)
+			os.Exit(1)
+		}
+	}
 	goHandler := sensu.NewGoHandler(&config.PluginConfig, slackConfigOptions, checkArgs, sendMessage)
 	goHandler.Execute()
 }
 
-func checkArgs(_ *corev2.Event) error {
+func checkArgs(event *corev2.Event) error {
 	// Support deprecated environment variables
 	if webhook := os.Getenv("SENSU_SLACK_WEBHOOK_URL"); webhook != "" {
 		config.slackwebHookURL = webhook
@@ -133,17 +1121,313 @@ func checkArgs(_ *corev2.Event) error {
 		config.slackIconURL = icon
 	}
 
-	if len(config.slackwebHookURL) == 0 {
-		return fmt.Errorf("--%s or SLACK_WEBHOOK_URL environment variable is required", webHookURL)
+	if len(config.configFile) > 0 {
+		if err := loadConfigFile(event, config.configFile); err != nil {
+			return fmt.Errorf("error loading --%s: %v", configFile, err)
+		}
+	}
+
+	// Annotations are set by whoever controls the check or entity, so they
+	// may name templates but not have the handler read files for them
+	for _, option := range []string{templateProfiles, templatePartials} {
+		value, source := annotationOption(event, option)
+		if len(value) == 0 {
+			continue
+		}
+		var templates map[string]string
+		if err := json.Unmarshal([]byte(value), &templates); err != nil {
+			continue
+		}
+		for name, text := range templates {
+			if strings.HasPrefix(text, "@") {
+				return fmt.Errorf("--%s %q from a %s can't reference a file; set it by flag or environment variable instead", option, name, source)
+			}
+		}
+	}
+
+	// Dump the configuration before validating it, so that invalid
+	// configurations can be shared too
+	if dumpConfigWithoutEvent && !config.dumpConfig {
+		return fmt.Errorf("an event is required on stdin unless --%s is set", dumpConfigFlag)
+	}
+	if config.dumpConfig {
+		if dumpConfigWithoutEvent {
+			logf("%s: No event on stdin, so check and entity annotation overrides are not applied\n", config.PluginConfig.Name)
+			event = nil
+		}
+		out, err := dumpConfig(event)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+		return nil
+	}
+
+	if len(config.slackwebHookURL) > 0 && len(config.slackAPIToken) > 0 {
+		return fmt.Errorf("only one of --%s and --%s may be set", webHookURL, apiToken)
+	}
+
+	for _, target := range config.targets {
+		if !slices.Contains([]string{"slack", "discord", "teams", "file"}, target) {
+			return fmt.Errorf("unsupported --%s value %q: must be slack, discord, teams or file", targets, target)
+		}
+	}
+	if slices.Contains(deliveryTargets(), "slack") && len(config.slackwebHookURL) == 0 && len(config.slackAPIToken) == 0 {
+		return fmt.Errorf("--%s or SLACK_WEBHOOK_URL environment variable, or --%s or SLACK_API_TOKEN environment variable, is required", webHookURL, apiToken)
+	}
+	if slices.Contains(deliveryTargets(), "file") && len(config.outputFile) == 0 {
+		return fmt.Errorf("--%s file requires --%s", targets, outputFile)
+	}
+	for _, mention := range config.criticalMentions {
+		mention = strings.TrimSpace(mention)
+		if len(mention) > 0 && !strings.HasPrefix(mention, "<") && !mentionIDPattern.MatchString(mention) {
+			return fmt.Errorf("invalid --%s value %q: use a user or user group ID, or the full <@U0123ABCD> or <!subteam^S0456EFGH> form", mentionOnCritical, mention)
+		}
+	}
+	if slices.Contains(deliveryTargets(), "discord") && len(config.discordWebhookURL) == 0 {
+		return fmt.Errorf("--%s discord requires --%s", targets, discordWebhookURL)
+	}
+	if slices.Contains(deliveryTargets(), "teams") && len(config.teamsWebhookURL) == 0 {
+		return fmt.Errorf("--%s teams requires --%s", targets, teamsWebhookURL)
 	}
 
 	if len(config.sensuUIURL) == 0 {
 		return fmt.Errorf("--%s or SENSU_UI_URL environment variable is required", uiURL)
 	}
 
+	if len(config.maintenanceWindow) > 0 {
+		if _, _, err := parseMaintenanceWindow(config.maintenanceWindow); err != nil {
+			return fmt.Errorf("invalid --%s: %v", maintenanceWindow, err)
+		}
+	}
+
+	if len(config.maxRuntime) > 0 {
+		if d, err := time.ParseDuration(config.maxRuntime); err != nil || d < 0 {
+			return fmt.Errorf("invalid --%s %q: must be a positive duration such as 8s", maxRuntime, config.maxRuntime)
+		}
+	}
+
+	if _, err := regexp.Compile(config.successBodyRegex); err != nil {
+		return fmt.Errorf("invalid --%s: %v", successBodyRegex, err)
+	}
+
+	if len(config.timestampTimezone) > 0 {
+		if _, err := time.LoadLocation(config.timestampTimezone); err != nil {
+			return fmt.Errorf("invalid --%s %q: must be an IANA time zone name such as America/New_York", timestampTimezone, config.timestampTimezone)
+		}
+	}
+
+	if config.maxRetries < 0 {
+		return fmt.Errorf("invalid --%s %d: must not be negative", maxRetries, config.maxRetries)
+	}
+
+	if len(config.retryBackoff) > 0 {
+		if d, err := time.ParseDuration(config.retryBackoff); err != nil || d < 0 {
+			return fmt.Errorf("invalid --%s %q: must be a positive duration such as 500ms", retryBackoff, config.retryBackoff)
+		}
+	}
+
+	if len(config.onSuccessTimeout) > 0 {
+		if d, err := time.ParseDuration(config.onSuccessTimeout); err != nil || d < 0 {
+			return fmt.Errorf("invalid --%s %q: must be a positive duration such as 10s", onSuccessTimeout, config.onSuccessTimeout)
+		}
+	}
+
+	if len(config.retryAfterMax) > 0 {
+		if d, err := time.ParseDuration(config.retryAfterMax); err != nil || d < 0 {
+			return fmt.Errorf("invalid --%s %q: must be a positive duration such as 60s", retryAfterMax, config.retryAfterMax)
+		}
+	}
+
+	if len(config.proxyURL) > 0 {
+		if u, err := url.Parse(config.proxyURL); err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return fmt.Errorf("invalid --%s: must be a URL such as http://proxy.example.com:3128", proxyURL)
+		}
+	}
+
+	if config.failOnOversize && config.slackFieldValueMaxLength > 0 {
+		return fmt.Errorf("--%s can't be used with --%s", failOnOversize, fieldValueMaxLength)
+	}
+
+	if config.showActionButtons && len(config.ackURLTemplate) == 0 && len(config.resolveURLTemplate) == 0 {
+		return fmt.Errorf("--%s requires --%s or --%s", showActionButtons, ackURLTemplate, resolveURLTemplate)
+	}
+
+	if config.schemaVersion == "legacy" && config.messageFormat == "blocks" {
+		return fmt.Errorf("--%s blocks can't be used with --%s legacy", messageFormat, schemaVersion)
+	}
+
+	if config.repeatOccurrences < 0 {
+		return fmt.Errorf("invalid --%s %d: must not be negative", repeatOccurrences, config.repeatOccurrences)
+	}
+
+	if config.minStatus > 3 {
+		return fmt.Errorf("invalid --%s %d: must be between 0 and 3", minStatus, config.minStatus)
+	}
+
+	if len(config.caFile) > 0 {
+		if _, err := loadCAFile(config.caFile); err != nil {
+			return fmt.Errorf("invalid --%s: %v", caFile, err)
+		}
+	}
+	if config.insecureSkipVerify {
+		logf("%s: Warning: --%s is set, TLS certificates will not be verified\n", config.PluginConfig.Name, insecureSkipVerify)
+	}
+
+	for _, pattern := range config.redactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid --%s pattern %q: %v", redact, pattern, err)
+		}
+	}
+
+	if len(config.outputJQ) > 0 {
+		if _, err := gojq.Parse(config.outputJQ); err != nil {
+			return fmt.Errorf("invalid --%s: %v", outputJQ, err)
+		}
+	}
+
+	if config.deliverOncePerStatus && len(config.stateFile) == 0 {
+		return fmt.Errorf("--%s requires --%s", deliverOnce, stateFile)
+	}
+
+	if len(config.mentionWindow) > 0 {
+		if d, err := time.ParseDuration(config.mentionWindow); err != nil || d < 0 {
+			return fmt.Errorf("invalid --%s %q: must be a positive duration such as 30m", mentionWindow, config.mentionWindow)
+		}
+		if len(config.stateFile) == 0 {
+			return fmt.Errorf("--%s requires --%s", mentionWindow, stateFile)
+		}
+	}
+
+	if config.updateInPlace && (len(config.slackAPIToken) == 0 || len(config.stateFile) == 0) {
+		return fmt.Errorf("--%s requires --%s and --%s", updateInPlace, apiToken, stateFile)
+	}
+
+	if len(config.threadByLabel) > 0 {
+		if len(config.slackAPIToken) == 0 || len(config.stateFile) == 0 {
+			return fmt.Errorf("--%s requires --%s and --%s", threadByLabel, apiToken, stateFile)
+		}
+		if config.updateInPlace {
+			return fmt.Errorf("--%s can't be used with --%s", threadByLabel, updateInPlace)
+		}
+	}
+
+	for option, length := range map[string]int{
+		summaryLengthOK:       config.summaryLengthOK,
+		summaryLengthWarning:  config.summaryLengthWarning,
+		summaryLengthCritical: config.summaryLengthCritical,
+		summaryLengthUnknown:  config.summaryLengthUnknown,
+	} {
+		if length < -1 {
+			return fmt.Errorf("invalid --%s %d: must be a length, 0 to use --%s, or -1 for no truncation", option, length, summaryMaxLength)
+		}
+	}
+
+	// Malformed events are skipped later, so there's nothing to render for them
+	if event.Entity != nil && event.Check != nil {
+		if _, err := renderEventURL(event, config.uiEventPathTemplate); err != nil {
+			return fmt.Errorf("invalid --%s: %v", uiEventPathTemplate, err)
+		}
+	}
+
+	for option, color := range map[string]string{
+		colorOK:       config.colorOK,
+		colorWarning:  config.colorWarning,
+		colorCritical: config.colorCritical,
+		colorUnknown:  config.colorUnknown,
+	} {
+		if len(color) > 0 && !isHexColor(color) {
+			return fmt.Errorf("invalid --%s %q: expected a #rrggbb color", option, color)
+		}
+	}
+
+	for key, color := range config.priorityColors {
+		if !isHexColor(color) {
+			return fmt.Errorf("invalid --%s color %q for %q: expected a #rrggbb color", priorityColors, color, key)
+		}
+	}
+
+	if config.normalizeEmojiShortcodes {
+		normalizeEmojiOptions()
+	}
+
+	if config.selfTestOnStart && slices.Contains(deliveryTargets(), "slack") && !config.dryRun {
+		if err := selfTest(context.Background(), event); err != nil {
+			return fmt.Errorf("self-test failed: %v", err)
+		}
+	}
+
 	return nil
 }
 
+// statusName returns the name of a check status.
+func statusName(status uint32) string {
+	switch status {
+	case 0:
+		return "OK"
+	case 1:
+		return "WARNING"
+	case 2:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// statusSeverity ranks a check status by severity: OK, then WARNING, then
+// CRITICAL, with UNKNOWN and any other status the most severe.
+func statusSeverity(status uint32) uint32 {
+	if status > 3 {
+		return 3
+	}
+	return status
+}
+
+// shouldNotify reports whether the event is severe enough to notify about
+// under --min-status. A resolution is notified when the check's previous
+// status was severe enough to have been notified, or isn't known.
+func shouldNotify(event *corev2.Event) bool {
+	if event.Check.Status != 0 {
+		return statusSeverity(event.Check.Status) >= config.minStatus
+	}
+	previous, ok := previousStatus(event)
+	return !ok || statusSeverity(previous) >= config.minStatus
+}
+
+// previousStatus returns the check's status before the current execution,
+// from the check history, if it is known.
+func previousStatus(event *corev2.Event) (uint32, bool) {
+	// The last history entry is the current execution
+	history := event.Check.History
+	if len(history) < 2 {
+		return 0, false
+	}
+	return history[len(history)-2].Status, true
+}
+
+// statusTransitioned reports whether the check's status differs from its
+// previous status. The first event for a check, with no previous status,
+// counts as a transition.
+func statusTransitioned(event *corev2.Event) bool {
+	previous, ok := previousStatus(event)
+	return !ok || previous != event.Check.Status
+}
+
+// shouldRepeat reports whether an alert should be sent for the event when
+// alerts are repeated only every n occurrences. The first occurrence and
+// resolutions are always sent.
+func shouldRepeat(event *corev2.Event, n int) bool {
+	if n <= 0 || event.Check.Status == 0 || event.Check.Occurrences <= 1 {
+		return true
+	}
+	return event.Check.Occurrences%int64(n) == 0
+}
+
+// isSilenced reports whether the event's check has been silenced.
+func isSilenced(event *corev2.Event) bool {
+	return event.Check != nil && (event.Check.IsSilenced || len(event.Check.Silenced) > 0)
+}
+
 func formattedEventAction(event *corev2.Event) string {
 	switch event.Check.Status {
 	case 0:
@@ -161,75 +1445,677 @@ func eventKey(event *corev2.Event) string {
 	return fmt.Sprintf("%s/%s", event.Entity.Name, event.Check.Name)
 }
 
+// eventMetadataValue returns the value of the named label or annotation,
+// checking the check's labels and annotations before the entity's.
+func eventMetadataValue(event *corev2.Event, name string) string {
+	if len(name) == 0 {
+		return ""
+	}
+	for _, m := range []map[string]string{
+		event.Check.Labels,
+		event.Check.Annotations,
+		event.Entity.Labels,
+		event.Entity.Annotations,
+	} {
+		if value, ok := m[name]; ok && len(value) > 0 {
+			return value
+		}
+	}
+	return ""
+}
+
 func eventSummary(event *corev2.Event, maxLength int) string {
 	output := chomp(event.Check.Output)
-	if len(event.Check.Output) > maxLength {
-		output = output[0:maxLength] + "..."
+	if !config.fallbackNewlines {
+		output = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(output)
+	}
+	if runes := []rune(output); maxLength > 0 && len(runes) > maxLength {
+		if config.truncateWordBoundary {
+			output = truncateAtWordBoundary(output, maxLength) + "..."
+		} else {
+			output = string(runes[0:maxLength]) + "..."
+		}
 	}
 	return fmt.Sprintf("%s:%s", eventKey(event), output)
 }
 
+// truncateAtWordBoundary cuts s to maxLength characters, backing up to the
+// last whitespace before the cut if it is within wordBoundaryWindow, so that
+// words are not split.
+func truncateAtWordBoundary(s string, maxLength int) string {
+	runes := []rune(s)
+	if maxLength >= len(runes) {
+		return s
+	}
+	cut := runes[0:maxLength]
+	if unicode.IsSpace(runes[maxLength]) {
+		return strings.TrimRightFunc(string(cut), unicode.IsSpace)
+	}
+	for i := len(cut) - 1; i > 0 && maxLength-i <= wordBoundaryWindow; i-- {
+		if unicode.IsSpace(cut[i]) {
+			return strings.TrimRightFunc(string(cut[0:i]), unicode.IsSpace)
+		}
+	}
+	return string(cut)
+}
+
+// eventURL returns the event's URL in the Sensu UI, with the path rendered
+// from the UI event path template. If the template fails to render a valid
+// URL, the default path is used.
 func eventURL(event *corev2.Event) string {
-	return fmt.Sprintf("%s/n/%s/events/%s/%s", config.sensuUIURL, event.Entity.Namespace, event.Entity.Name, event.Check.Name)
+	link, err := renderEventURL(event, config.uiEventPathTemplate)
+	if err != nil {
+		logEventf(levelError, event, nil, "%s: Error processing --%s, using the default path: %s\n", config.PluginConfig.Name, uiEventPathTemplate, err)
+		link, _ = renderEventURL(event, defaultUIEventPathTemplate)
+	}
+	return link
+}
+
+// renderEventURL renders the path template for the event, appends it to the
+// UI URL, and checks that the result is a valid URL.
+func renderEventURL(event *corev2.Event, pathTemplate string) (string, error) {
+	if len(pathTemplate) == 0 {
+		pathTemplate = defaultUIEventPathTemplate
+	}
+	path, err := evalTemplate("ui-event-path", pathTemplate, newTemplateContext(event))
+	if err != nil {
+		return "", err
+	}
+	link := config.sensuUIURL + strings.TrimSpace(path)
+	if _, err := url.Parse(link); err != nil {
+		return "", fmt.Errorf("invalid event URL: %v", err)
+	}
+	return link, nil
+}
+
+// namespacePrefix returns the event's namespace formatted for prefixing the
+// message, or an empty string if namespaces are not included.
+func namespacePrefix(event *corev2.Event) string {
+	if !config.includeNamespace || len(event.Entity.Namespace) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[%s] ", event.Entity.Namespace)
+}
+
+// subscriptionEmojiPrefix returns the emoji configured for the first of the
+// check's subscriptions, then the entity's, that has one.
+func subscriptionEmojiPrefix(event *corev2.Event) string {
+	if len(config.subscriptionEmoji) == 0 {
+		return ""
+	}
+	subscriptions := append([]string{}, event.Check.Subscriptions...)
+	subscriptions = append(subscriptions, event.Entity.Subscriptions...)
+	for _, subscription := range subscriptions {
+		if emoji, ok := config.subscriptionEmoji[subscription]; ok && len(emoji) > 0 {
+			return emoji
+		}
+	}
+	return ""
 }
 
 func formattedMessage(event *corev2.Event) string {
-	return fmt.Sprintf("%s - %s", formattedEventAction(event), eventSummary(event, 100))
+	return fmt.Sprintf("%s - %s%s", formattedEventAction(event), namespacePrefix(event), eventSummary(event, summaryMaxLengthFor(event.Check.Status)))
+}
+
+// summaryMaxLengthFor returns the summary truncation length for the status,
+// falling back to --summary-max-length when none is set for its severity. A
+// length of -1 for the severity disables truncation, as 0 does for
+// --summary-max-length.
+func summaryMaxLengthFor(status uint32) int {
+	length := config.summaryLengthUnknown
+	switch status {
+	case 0:
+		length = config.summaryLengthOK
+	case 1:
+		length = config.summaryLengthWarning
+	case 2:
+		length = config.summaryLengthCritical
+	}
+	if length < 0 {
+		return 0
+	}
+	if length > 0 {
+		return length
+	}
+	return config.summaryMaxLength
 }
 
 func messageColor(event *corev2.Event) string {
-	switch event.Check.Status {
+	if color, ok := templateColor(event); ok {
+		return color
+	}
+	if color, ok := annotationColor(event); ok {
+		return color
+	}
+	if color, ok := priorityColor(event); ok {
+		return color
+	}
+	return statusColor(event.Check.Status)
+}
+
+// statusColor returns the configured color for the status, or the default
+// color for it when none is configured.
+func statusColor(status uint32) string {
+	color, fallback := config.colorUnknown, defaultColorUnknown
+	switch status {
 	case 0:
-		return "#36a64f"
+		color, fallback = config.colorOK, defaultColorOK
 	case 1:
-		return "#ffcc00"
+		color, fallback = config.colorWarning, defaultColorWarning
 	case 2:
-		return "#ff0000"
-	default:
-		return "#6600cc"
+		color, fallback = config.colorCritical, defaultColorCritical
+	}
+	if len(color) == 0 {
+		return fallback
 	}
+	return color
 }
 
-func messageAttachment(event *corev2.Event) slack.Attachment {
-	description, err := templates.EvalTemplate("description", config.slackDescriptionTemplate, event)
+// executedTime returns the Unix time at which the event's check was executed,
+// falling back to the event timestamp and then the current time when unset.
+func executedTime(event *corev2.Event) int64 {
+	if event.Check.Executed > 0 {
+		return event.Check.Executed
+	}
+	if event.Timestamp > 0 {
+		return event.Timestamp
+	}
+	return now().Unix()
+}
+
+// channelMention returns the mention to include in the message for the event,
+// or an empty string if the channel should not be alerted.
+func channelMention(event *corev2.Event) string {
+	if config.mentionFirstOccurrence && event.Check.Occurrences > 1 {
+		return ""
+	}
+	if config.slackAlertWarning && event.Check.Status == 1 {
+		return "<!channel>"
+	}
+	return criticalMentions(event)
+}
+
+// criticalMentions returns the mentions for a critical event: @channel when
+// alerting on critical events, followed by the configured users and groups.
+// Bare user and user group IDs are wrapped in Slack's mention syntax.
+func criticalMentions(event *corev2.Event) string {
+	if event.Check.Status != 2 {
+		return ""
+	}
+	var mentions []string
+	if config.slackAlertCritical {
+		mentions = append(mentions, "<!channel>")
+	}
+	for _, mention := range config.criticalMentions {
+		mention = strings.TrimSpace(mention)
+		switch {
+		case len(mention) == 0:
+			continue
+		case strings.HasPrefix(mention, "<"):
+		case !mentionIDPattern.MatchString(mention):
+			continue
+		case strings.HasPrefix(mention, "S"):
+			mention = "<!subteam^" + mention + ">"
+		default:
+			mention = "<@" + mention + ">"
+		}
+		mentions = append(mentions, mention)
+	}
+	return strings.Join(mentions, " ")
+}
+
+// renderDescription returns the event's description, before any prefixes or
+// banners are added to it.
+func renderDescription(event *corev2.Event) string {
+	if config.accessible {
+		return accessibleDescription(event)
+	}
+	description, err := evalTemplate("description", selectDescriptionTemplate(event), newTemplateContext(fencedOutputEvent(event)))
 	if err != nil {
-		fmt.Printf("%s: Error processing template: %s", config.PluginConfig.Name, err)
+		logEventf(levelError, event, nil, "%s: Error processing template: %s\n", config.PluginConfig.Name, err)
 	}
+	return strings.Replace(description, `\n`, "\n", -1)
+}
 
-	description = strings.Replace(description, `\n`, "\n", -1)
+// messageDescription returns the rendered description along with any
+// namespace, emoji and maintenance prefixes, as shown in the message body.
+func messageDescription(event *corev2.Event) string {
+	description := namespacePrefix(event) + renderDescription(event)
+	if emoji := subscriptionEmojiPrefix(event); len(emoji) > 0 && !config.accessible {
+		description = emoji + " " + description
+	}
+	if inMaintenanceWindow() {
+		banner := maintenanceBanner
+		if config.accessible {
+			banner = accessibleMaintenanceBanner
+		}
+		description = banner + "\n" + description
+	}
+	return description
+}
+
+func messageAttachment(event *corev2.Event) slack.Attachment {
 	attachment := slack.Attachment{
-		Text:       description,
-		Fallback:   formattedMessage(event),
-		Color:      messageColor(event),
+		Text:     messageDescription(event),
+		Fallback: formattedMessage(event),
+		Color:    messageColor(event),
 		MarkdownIn: []string{
 			"text",
 		},
-		Actions:    []slack.AttachmentAction{
+	}
+	if event.Check.Status >= config.buttonMinStatus {
+		attachment.Actions = []slack.AttachmentAction{
 			{
-				Text:  "View in Sensu",
-				Type:  "button",
-				URL:   eventURL(event),
+				Text: "View in Sensu",
+				Type: "button",
+				URL:  eventURL(event),
 			},
-		},
+		}
+	}
+	if config.accessible || config.schemaVersion == "legacy" {
+		attachment.MarkdownIn = nil
+	}
+	attachment.Actions = append(attachment.Actions, sensuActions(event)...)
+	attachment.Actions = append(attachment.Actions, annotationLinkActions(event)...)
+	var footer []string
+	if config.showExecutingAgent {
+		footer = append(footer, executingAgentContext(event))
+	}
+	if config.handlerFooter {
+		footer = append(footer, handlerContextFooter(event))
+	}
+	if text := templateFooter(event); len(text) > 0 {
+		footer = append(footer, text)
 	}
+	attachment.Footer = strings.Join(footer, " | ")
+	if len(attachment.Footer) > 0 {
+		attachment.FooterIcon = config.footerIconURL
+	}
+	if config.tsFromExecuted {
+		attachment.Ts = json.Number(strconv.FormatInt(executedTime(event), 10))
+	}
+	attachment.Fields = append(attachment.Fields, annotationAttachmentFields(event)...)
+	attachment.Fields = append(attachment.Fields, auditFields(event)...)
+	attachment.Fields = truncateFieldValues(limitFields(sortAttachmentFields(attachment.Fields)))
 	return attachment
 }
 
+// writeOutputFile appends the message to the file as a single line of JSON.
+func writeOutputFile(path string, msg *slack.WebhookMessage) error {
+	line, err := marshalMessage(msg)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// redactSecrets replaces any secret configuration values found in s.
+func redactSecrets(s string) string {
+	secrets := []string{config.slackwebHookURL, config.slackAPIToken, config.proxyURL, config.discordWebhookURL, config.teamsWebhookURL}
+	secrets = append(secrets, webhookURLs(config.slackwebHookURL)...)
+	for _, url := range config.channelWebhookURLs {
+		secrets = append(secrets, url)
+	}
+	for _, secret := range secrets {
+		if len(secret) > 0 {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+	}
+	return s
+}
+
+// deliveryTargets returns where messages are delivered: the configured
+// targets, or else the output file if one is set, or else Slack.
+func deliveryTargets() []string {
+	if len(config.targets) > 0 {
+		return config.targets
+	}
+	if len(config.outputFile) > 0 {
+		return []string{"file"}
+	}
+	return []string{"slack"}
+}
+
+// retryDurations returns the configured retry backoff and the longest
+// Retry-After to wait for.
+func retryDurations() (time.Duration, time.Duration, error) {
+	var backoff, maxRetryAfter time.Duration
+	if len(config.retryBackoff) > 0 {
+		var err error
+		if backoff, err = time.ParseDuration(config.retryBackoff); err != nil {
+			return 0, 0, fmt.Errorf("invalid --%s: %v", retryBackoff, err)
+		}
+	}
+	if len(config.retryAfterMax) > 0 {
+		var err error
+		if maxRetryAfter, err = time.ParseDuration(config.retryAfterMax); err != nil {
+			return 0, 0, fmt.Errorf("invalid --%s: %v", retryAfterMax, err)
+		}
+	}
+	return backoff, maxRetryAfter, nil
+}
+
+// deliverToSlack sends the message to Slack, retrying as configured. With a
+// bot token, it updates the message at updateTS if one is given, and returns
+// the channel and timestamp of the message posted or updated.
+func deliverToSlack(ctx context.Context, event *corev2.Event, channel, updateTS string, hookmsg *slack.WebhookMessage) (string, string, error) {
+	backoff, maxRetryAfter, err := retryDurations()
+	if err != nil {
+		return "", "", err
+	}
+	var postedChannel, postedTS string
+	attempts, err := withRetries(ctx, backoff, maxRetryAfter, func() error {
+		if len(config.slackAPIToken) > 0 {
+			var err error
+			if len(updateTS) > 0 {
+				postedChannel, postedTS, err = updateMessage(ctx, event, updateTS, hookmsg)
+			} else {
+				postedChannel, postedTS, err = postMessage(ctx, event, hookmsg)
+			}
+			return err
+		}
+		return postWebhooks(ctx, webhookURLs(resolveWebhookURL(channel)), newHTTPClient(event), hookmsg)
+	})
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", "", &maxRuntimeError{action: "sending Slack message"}
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to send Slack message after %d attempt(s): %v", attempts, err)
+	}
+	return postedChannel, postedTS, nil
+}
+
+// maxRuntimeError reports that handling an event was abandoned because it
+// took longer than --max-runtime.
+type maxRuntimeError struct {
+	action string
+	err    error
+}
+
+func (e *maxRuntimeError) Error() string {
+	msg := fmt.Sprintf("Aborted %s: exceeded max runtime of %s", e.action, config.maxRuntime)
+	if e.err != nil {
+		msg += ": " + e.err.Error()
+	}
+	return msg
+}
+
+func (e *maxRuntimeError) Unwrap() error {
+	return e.err
+}
+
 func sendMessage(event *corev2.Event) error {
-	hookmsg := &slack.WebhookMessage{
-		Attachments: []slack.Attachment{messageAttachment(event)},
-		Channel:     config.slackChannel,
-		IconURL:     config.slackIconURL,
-		Username:    config.slackUsername,
+	if config.dumpConfig {
+		return nil
+	}
+
+	correlationID = newCorrelationID()
+
+	ctx := context.Background()
+	if len(config.maxRuntime) > 0 {
+		timeout, err := time.ParseDuration(config.maxRuntime)
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %v", maxRuntime, err)
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+
+	// Whatever was cut short by --max-runtime, whether waiting for the state
+	// file or sending, is reported as such
+	err := notify(ctx, event)
+	var runtimeErr *maxRuntimeError
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && !errors.As(err, &runtimeErr) {
+		return &maxRuntimeError{action: "handling event", err: err}
+	}
+	return err
+}
+
+// notify sends the notification for the event to every delivery target,
+// giving up on whatever is still in progress when ctx is done.
+func notify(ctx context.Context, event *corev2.Event) error {
+	if event.Entity == nil || event.Check == nil {
+		logf("%s: Skipping malformed event without an entity or check\n", config.PluginConfig.Name)
+		return nil
+	}
+
+	if !shouldNotify(event) {
+		logf("Skipping notification for %s: status %d is below --%s %d\n", eventKey(event), event.Check.Status, minStatus, config.minStatus)
+		return nil
+	}
+
+	if config.notifyOnTransition && !statusTransitioned(event) {
+		logf("Skipping notification for %s: no transition from status %d\n", eventKey(event), event.Check.Status)
+		return nil
+	}
+
+	if !shouldRepeat(event, config.repeatOccurrences) {
+		logf("Skipping notification for %s: occurrence %d is not a multiple of --%s %d\n", eventKey(event), event.Check.Occurrences, repeatOccurrences, config.repeatOccurrences)
+		return nil
+	}
+
+	if config.respectSilencing && isSilenced(event) {
+		logf("Skipping notification for %s: silenced\n", eventKey(event))
+		return nil
 	}
 
-	err := slack.PostWebhook(config.slackwebHookURL, hookmsg)
+	store := newStateStore(ctx)
+	since, err := sinceLastNotification(store, event)
 	if err != nil {
-		return fmt.Errorf("Failed to send Slack message: %v", err)
+		logf("%s: Error reading state file: %s\n", config.PluginConfig.Name, err)
 	}
+	sinceLastNotified = since
 
-	// FUTURE: send to AH
-	fmt.Printf("Notification sent to Slack channel %s\n", config.slackChannel)
+	if store != nil && config.deliverOncePerStatus {
+		claimed, err := claimDelivery(store, event)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			logf("Skipping notification for %s: status %d already notified\n", eventKey(event), event.Check.Status)
+			return nil
+		}
+		// A delivery that isn't recorded, because it failed or was skipped,
+		// gives up its claim so that the next event can try again.
+		defer func(event *corev2.Event) {
+			if err := releaseDelivery(store, event); err != nil {
+				logf("%s: Error updating state file: %s\n", config.PluginConfig.Name, err)
+			}
+		}(event)
+	}
 
-	return nil
+	event = renderableEvent(event)
+	if config.skipEmpty && len(strings.TrimSpace(renderDescription(event))) == 0 {
+		logf("Skipping notification for %s: description is empty\n", eventKey(event))
+		return nil
+	}
+	channel := resolveChannel(event)
+	// With a bot token, the posted message's timestamp is known, so a
+	// resolution can be threaded under the alert it resolves. Events sharing
+	// a thread label are all threaded under the first alert.
+	threaded := store != nil && len(config.slackAPIToken) > 0
+	_, sharedThread := threadKey(event)
+	var threadTS, updateTS string
+	if threaded && config.updateInPlace {
+		updateChannel, ts, err := alertThread(store, event)
+		if err != nil {
+			return err
+		}
+		if len(ts) > 0 {
+			channel, updateTS = updateChannel, ts
+		}
+	} else if threaded && (event.Check.Status == 0 || sharedThread) {
+		threadChannel, ts, err := alertThread(store, event)
+		if err != nil {
+			return err
+		}
+		if len(ts) > 0 {
+			channel, threadTS = threadChannel, ts
+		}
+	}
+	mention := channelMention(event)
+	var window time.Duration
+	if len(config.mentionWindow) > 0 && len(mention) > 0 && store != nil {
+		var err error
+		if window, err = time.ParseDuration(config.mentionWindow); err != nil {
+			return fmt.Errorf("invalid --%s: %v", mentionWindow, err)
+		}
+		// Each user, group or @channel has its own window, so only the
+		// ones mentioned recently are dropped
+		var kept, omitted []string
+		for _, m := range strings.Fields(mention) {
+			recent, err := mentionedWithin(store, channel, m, window)
+			if err != nil {
+				return err
+			}
+			if recent {
+				omitted = append(omitted, m)
+			} else {
+				kept = append(kept, m)
+			}
+		}
+		if len(omitted) > 0 {
+			logf("Omitting %s from notification for %s: already mentioned in %s within %s\n", strings.Join(omitted, " "), eventKey(event), channel, config.mentionWindow)
+		}
+		mention = strings.Join(kept, " ")
+	}
+	hookmsg := &slack.WebhookMessage{
+		Text:         mention,
+		Attachments:  []slack.Attachment{messageAttachment(event)},
+		Channel:      channel,
+		IconURL:      config.slackIconURL,
+		Username:     config.slackUsername,
+		ResponseType: config.responseType,
+	}
+	hookmsg.ThreadTimestamp = threadTS
+	if config.messageFormat == "blocks" {
+		// Slack shows the top level text in notifications when the message
+		// has blocks
+		hookmsg.Text = strings.TrimSpace(mention + " " + formattedMessage(event))
+		hookmsg.Attachments = nil
+		hookmsg.Blocks = &slack.Blocks{BlockSet: messageBlocks(event)}
+	} else if text := hookmsg.Attachments[0].Text; config.autoLayout && len([]rune(text)) <= config.autoLayoutThreshold {
+		// Short descriptions read better without the attachment around them
+		hookmsg.Text = strings.TrimSpace(mention + " " + text)
+		hookmsg.Attachments = nil
+	}
+
+	if config.failOnOversize {
+		if err := oversizeError(hookmsg); err != nil {
+			return err
+		}
+	}
+
+	if config.logRequestBody {
+		body, err := marshalMessage(hookmsg)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal Slack message: %v", err)
+		}
+		logf("debug: request body: %s\n", redactSecrets(string(body)))
+	}
+
+	if config.dryRun {
+		body, err := marshalMessage(hookmsg)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal Slack message: %v", err)
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, body, "", "  "); err != nil {
+			return fmt.Errorf("Failed to marshal Slack message: %v", err)
+		}
+		fmt.Println(indented.String())
+		return nil
+	}
+
+	// Rendering can be slow enough to use up the time left
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	delivered := false
+	var slackMessage *deliveredMessage
+	for _, target := range deliveryTargets() {
+		switch target {
+		case "file":
+			if err := writeOutputFile(config.outputFile, hookmsg); err != nil {
+				err = fmt.Errorf("Failed to write Slack message to %s: %v", config.outputFile, err)
+				logEventf(levelError, event, logFields{"channel": channel, "file": config.outputFile}, "%v\n", err)
+				errs = append(errs, err)
+				continue
+			}
+			delivered = true
+			logEventf(levelInfo, event, logFields{"channel": channel, "file": config.outputFile}, "Notification for Slack channel %s written to %s\n", channel, config.outputFile)
+		case "discord", "teams":
+			if err := deliverToRelay(ctx, event, target, hookmsg); err != nil {
+				logEventf(levelError, event, logFields{"target": target}, "%v\n", err)
+				errs = append(errs, err)
+				continue
+			}
+			delivered = true
+			logEventf(levelInfo, event, logFields{"target": target}, "Notification sent to %s\n", target)
+		case "slack":
+			postedChannel, postedTS, err := deliverToSlack(ctx, event, channel, updateTS, hookmsg)
+			if err != nil {
+				logEventf(levelError, event, logFields{"channel": channel}, "%v\n", err)
+				errs = append(errs, err)
+				continue
+			}
+
+			delivered = true
+			// FUTURE: send to AH
+			logEventf(levelInfo, event, logFields{"channel": channel}, "Notification sent to Slack channel %s\n", channel)
+
+			slackMessage = &deliveredMessage{Channel: channel, TS: postedTS}
+			if len(postedChannel) > 0 {
+				slackMessage.Channel = postedChannel
+			}
+
+			if threaded {
+				if err := recordThread(store, event, postedChannel, postedTS); err != nil {
+					return fmt.Errorf("Failed to update state file: %v", err)
+				}
+			}
+		}
+	}
+	// State is recorded once any target has the message, so that targets
+	// which failed don't cause the others to be sent it again.
+	if delivered && store != nil {
+		if err := recordNotification(store, event); err != nil {
+			errs = append(errs, fmt.Errorf("Failed to update state file: %v", err))
+		}
+	}
+	if delivered && window > 0 {
+		for _, m := range strings.Fields(mention) {
+			if err := recordMention(store, channel, m); err != nil {
+				errs = append(errs, fmt.Errorf("Failed to update state file: %v", err))
+				break
+			}
+		}
+	}
+
+	// The on-success command runs once the delivery is recorded, so that a
+	// failing command doesn't get the message sent again on the next event.
+	if slackMessage != nil && len(config.onSuccessCommand) > 0 {
+		if err := runSuccessCommand(ctx, event, *slackMessage); err != nil {
+			if config.onSuccessRequired {
+				errs = append(errs, fmt.Errorf("On-success command failed: %w", err))
+			} else {
+				logf("%s: On-success command failed: %v\n", config.PluginConfig.Name, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
 }
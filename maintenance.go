@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	maintenanceBanner           = ":warning: In maintenance window"
+	accessibleMaintenanceBanner = "Note: in maintenance window"
+)
+
+// parseMaintenanceWindow parses a maintenance window given as two RFC 3339
+// timestamps separated by a slash.
+func parseMaintenanceWindow(window string) (time.Time, time.Time, error) {
+	parts := strings.Split(window, "/")
+	if len(parts) != 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("expected start/end, got %q", window)
+	}
+	start, err := time.Parse(time.RFC3339, strings.TrimSpace(parts[0]))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start time: %v", err)
+	}
+	end, err := time.Parse(time.RFC3339, strings.TrimSpace(parts[1]))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end time: %v", err)
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end time must be after start time")
+	}
+	return start, end, nil
+}
+
+// inMaintenanceWindow reports whether the current time is within the
+// configured maintenance window.
+func inMaintenanceWindow() bool {
+	if len(config.maintenanceWindow) == 0 {
+		return false
+	}
+	start, end, err := parseMaintenanceWindow(config.maintenanceWindow)
+	if err != nil {
+		return false
+	}
+	t := now()
+	return !t.Before(start) && t.Before(end)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	start, end, err := parseMaintenanceWindow("2026-10-15T22:00:00Z/2026-10-16T02:00:00Z")
+	assert.NoError(err)
+	assert.Equal(time.Date(2026, 10, 15, 22, 0, 0, 0, time.UTC), start)
+	assert.Equal(time.Date(2026, 10, 16, 2, 0, 0, 0, time.UTC), end)
+
+	_, _, err = parseMaintenanceWindow("2026-10-15T22:00:00Z")
+	assert.Error(err)
+	_, _, err = parseMaintenanceWindow("tonight/tomorrow")
+	assert.Error(err)
+	_, _, err = parseMaintenanceWindow("2026-10-16T02:00:00Z/2026-10-15T22:00:00Z")
+	assert.Error(err)
+}
+
+func TestMaintenanceBanner(t *testing.T) {
+	assert := assert.New(t)
+	event := corev2.FixtureEvent("entity1", "check1")
+	config.slackDescriptionTemplate = "{{ .Check.Name }} is failing"
+	config.maintenanceWindow = "2026-10-15T22:00:00Z/2026-10-16T02:00:00Z"
+	defer func() {
+		config.maintenanceWindow = ""
+		now = time.Now
+	}()
+
+	now = func() time.Time { return time.Date(2026, 10, 15, 23, 30, 0, 0, time.UTC) }
+	assert.True(inMaintenanceWindow())
+	assert.Equal(":warning: In maintenance window\ncheck1 is failing", messageAttachment(event).Text)
+
+	now = func() time.Time { return time.Date(2026, 10, 16, 2, 0, 0, 0, time.UTC) }
+	assert.False(inMaintenanceWindow())
+	assert.Equal("check1 is failing", messageAttachment(event).Text)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/itchyny/gojq"
+	corev2 "github.com/sensu/core/v2"
+	"regexp"
+	"strings"
+)
+
+// redactAnnotation is the check annotation holding a JSON array of extra
+// redaction patterns for the check's output.
+const redactAnnotation = "slack_redact_patterns"
+
+// renderableEvent returns a copy of the event with the check output
+// transformed as configured, for use when rendering messages. The original
+// event is not modified.
+func renderableEvent(event *corev2.Event) *corev2.Event {
+	output := event.Check.Output
+	if len(config.outputJQ) > 0 {
+		output = filterOutput(output, config.outputJQ)
+	}
+	output = redactOutput(output, redactPatterns(event))
+	if len(chomp(output)) == 0 && len(config.emptyOutputText) > 0 {
+		output = config.emptyOutputText
+	}
+	if output == event.Check.Output {
+		return event
+	}
+	return withOutput(event, output)
+}
+
+// withOutput returns a shallow copy of the event with the check output
+// replaced.
+func withOutput(event *corev2.Event, output string) *corev2.Event {
+	check := *event.Check
+	check.Output = output
+	rendered := *event
+	rendered.Check = &check
+	return &rendered
+}
+
+// fencedOutputEvent returns a copy of the event with the check output in a
+// code block tagged with the configured language, for description templates.
+// Empty output, and the placeholder for it, are left as they are.
+func fencedOutputEvent(event *corev2.Event) *corev2.Event {
+	output := chomp(event.Check.Output)
+	if len(config.outputLanguage) == 0 || len(output) == 0 || output == config.emptyOutputText {
+		return event
+	}
+	return withOutput(event, "```"+config.outputLanguage+"\n"+output+"\n```")
+}
+
+// redactPatterns returns the configured redaction patterns, followed by any
+// added by the check's slack_redact_patterns annotation. Invalid patterns
+// from the annotation are skipped with a warning.
+func redactPatterns(event *corev2.Event) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, pattern := range config.redactPatterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			patterns = append(patterns, re)
+		}
+	}
+	value := event.Check.Annotations[redactAnnotation]
+	if len(value) == 0 {
+		return patterns
+	}
+	var extra []string
+	if err := json.Unmarshal([]byte(value), &extra); err != nil {
+		logf("%s: Ignoring %s annotation, expected a JSON array of regular expressions: %s\n", config.PluginConfig.Name, redactAnnotation, err)
+		return patterns
+	}
+	for _, pattern := range extra {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logf("%s: Ignoring invalid %s pattern %q: %s\n", config.PluginConfig.Name, redactAnnotation, pattern, err)
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+// redactOutput replaces every match of the patterns in the output.
+func redactOutput(output string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		output = re.ReplaceAllString(output, "[REDACTED]")
+	}
+	return output
+}
+
+// filterOutput applies a jq expression to check output containing JSON. The
+// output is returned unchanged if it isn't JSON or the expression fails.
+func filterOutput(output, expression string) string {
+	var input interface{}
+	if err := json.Unmarshal([]byte(output), &input); err != nil {
+		return output
+	}
+	query, err := gojq.Parse(expression)
+	if err != nil {
+		logf("%s: Error parsing jq expression: %s\n", config.PluginConfig.Name, err)
+		return output
+	}
+
+	var results []string
+	iter := query.Run(input)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			logf("%s: Error applying jq expression to check output: %s\n", config.PluginConfig.Name, err)
+			return output
+		}
+		if s, ok := v.(string); ok {
+			results = append(results, s)
+			continue
+		}
+		b, err := gojq.Marshal(v)
+		if err != nil {
+			return output
+		}
+		results = append(results, string(b))
+	}
+	return strings.Join(results, "\n")
+}
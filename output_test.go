@@ -0,0 +1,92 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFilterOutput(t *testing.T) {
+	assert := assert.New(t)
+	output := `{"status":"degraded","checks":{"db":"down","cache":"up"},"latency_ms":[12,480]}`
+
+	assert.Equal("down", filterOutput(output, ".checks.db"))
+	assert.Equal("480", filterOutput(output, ".latency_ms | max"))
+	assert.Equal(`{"cache":"up","db":"down"}`, filterOutput(output, ".checks"))
+	assert.Equal("degraded\ndown", filterOutput(output, ".status, .checks.db"))
+
+	// Non-JSON output and failing expressions leave the output unchanged
+	assert.Equal("CRITICAL: disk is full", filterOutput("CRITICAL: disk is full", ".status"))
+	assert.Equal(output, filterOutput(output, ".status | tonumber"))
+	assert.Equal(output, filterOutput(output, ".checks["))
+}
+
+func TestRenderableEventJQ(t *testing.T) {
+	assert := assert.New(t)
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Output = `{"message":"replication lag 42s"}`
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	config.outputJQ = ".message"
+	defer func() { config.outputJQ = "" }()
+
+	rendered := renderableEvent(event)
+	assert.Equal("replication lag 42s", messageAttachment(rendered).Text)
+	assert.Equal(`{"message":"replication lag 42s"}`, event.Check.Output)
+}
+
+func TestRenderableEventEmptyOutput(t *testing.T) {
+	assert := assert.New(t)
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Output = "\n"
+	config.slackDescriptionTemplate = "{{ .Check.Name }}: {{ .Check.Output }}"
+	config.emptyOutputText = "(no output)"
+	defer func() { config.emptyOutputText = "" }()
+
+	rendered := renderableEvent(event)
+	assert.Equal("(no output)", rendered.Check.Output)
+	assert.Equal("check1: (no output)", messageAttachment(rendered).Text)
+	assert.Equal("RESOLVED - entity1/check1:(no output)", formattedMessage(rendered))
+
+	event.Check.Output = "all good\n"
+	assert.Same(event, renderableEvent(event))
+}
+
+func TestOutputLanguage(t *testing.T) {
+	assert := assert.New(t)
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Output = "{\"disk\": 97}\n"
+	config.slackDescriptionTemplate = "{{ .Check.Name }}\n{{ .Check.Output }}"
+
+	assert.Equal("check1\n{\"disk\": 97}\n", messageAttachment(event).Text)
+
+	config.outputLanguage = "json"
+	defer func() { config.outputLanguage = "" }()
+	attachment := messageAttachment(event)
+	assert.Equal("check1\n```json\n{\"disk\": 97}\n```", attachment.Text)
+	assert.Equal("RESOLVED - entity1/check1:{\"disk\": 97}", attachment.Fallback)
+
+	// Only the description is fenced, not the other templates
+	config.footerTemplate = "{{ .Check.Output }}"
+	defer func() { config.footerTemplate = "" }()
+	assert.Equal("{\"disk\": 97}", messageAttachment(event).Footer)
+
+	event.Check.Output = ""
+	assert.Equal("check1\n", messageAttachment(event).Text)
+}
+
+func TestRedactOutput(t *testing.T) {
+	assert := assert.New(t)
+	config.redactPatterns = []string{`password=\S+`}
+	defer func() { config.redactPatterns = nil }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Output = "login failed: password=hunter2 token=abc123"
+	assert.Equal("login failed: [REDACTED] token=abc123", renderableEvent(event).Check.Output)
+
+	// Check patterns are merged with the global ones, skipping invalid ones
+	event.Check.Annotations = map[string]string{
+		redactAnnotation: `["token=[a-z0-9]+", "("]`,
+	}
+	assert.Equal("login failed: [REDACTED] [REDACTED]", renderableEvent(event).Check.Output)
+	assert.Equal("login failed: password=hunter2 token=abc123", event.Check.Output)
+}
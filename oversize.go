@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"github.com/slack-go/slack"
+)
+
+// Slack truncates message text longer than this
+const messageTextMaxLength = 40000
+
+// oversizeError returns an error describing the first part of the message
+// that exceeds Slack's limits, or nil if the message fits. It is used with
+// --fail-on-oversize in place of truncating the message.
+func oversizeError(msg *slack.WebhookMessage) error {
+	if err := checkLength("message text", msg.Text, messageTextMaxLength); err != nil {
+		return err
+	}
+	for _, attachment := range msg.Attachments {
+		if err := checkLength("attachment text", attachment.Text, messageTextMaxLength); err != nil {
+			return err
+		}
+	}
+	if msg.Blocks == nil {
+		return nil
+	}
+	for _, block := range msg.Blocks.BlockSet {
+		switch b := block.(type) {
+		case *slack.HeaderBlock:
+			if err := checkLength("header block text", b.Text.Text, headerTextMaxLength); err != nil {
+				return err
+			}
+		case *slack.SectionBlock:
+			if err := checkLength("section block text", b.Text.Text, sectionTextMaxLength); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkLength(name, text string, maxLength int) error {
+	if length := len([]rune(text)); length > maxLength {
+		return fmt.Errorf("Slack message is too long: the %s is %d characters, more than Slack's limit of %d", name, length, maxLength)
+	}
+	return nil
+}
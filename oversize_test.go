@@ -0,0 +1,53 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFailOnOversize(t *testing.T) {
+	assert := assert.New(t)
+	posts := 0
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiStub.Close()
+
+	config.slackwebHookURL = apiStub.URL
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	defer func() {
+		config.failOnOversize = false
+		config.messageFormat = ""
+	}()
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+	event.Check.Output = strings.Repeat("x", messageTextMaxLength+1)
+
+	assert.NoError(sendMessage(event))
+	assert.Equal(1, posts)
+
+	config.failOnOversize = true
+	err := sendMessage(event)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "the attachment text is 40001 characters, more than Slack's limit of 40000")
+	}
+	assert.Equal(1, posts)
+
+	// Blocks have much lower limits, and are no longer truncated to fit
+	config.messageFormat = "blocks"
+	event.Check.Output = strings.Repeat("x", sectionTextMaxLength+1)
+	err = sendMessage(event)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "the section block text is 3001 characters")
+	}
+	assert.Equal(1, posts)
+
+	event.Check.Output = "disk is full"
+	assert.NoError(sendMessage(event))
+	assert.Equal(2, posts)
+}
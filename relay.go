@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"net/http"
+	"strings"
+)
+
+// teamsMessageCard is a Microsoft Teams incoming webhook message card.
+type teamsMessageCard struct {
+	Type            string         `json:"@type"`
+	Context         string         `json:"@context"`
+	ThemeColor      string         `json:"themeColor,omitempty"`
+	Summary         string         `json:"summary"`
+	Text            string         `json:"text,omitempty"`
+	Sections        []teamsSection `json:"sections,omitempty"`
+	PotentialAction []teamsAction  `json:"potentialAction,omitempty"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts"`
+	Text  string      `json:"text,omitempty"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+type teamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+// teamsCard converts the Slack message to a Teams message card. The
+// attachment's text, color, fields, link buttons and footer carry over;
+// Block Kit blocks have no Teams equivalent, so only the fallback text of
+// a blocks message is sent.
+func teamsCard(msg *slack.WebhookMessage) teamsMessageCard {
+	card := teamsMessageCard{
+		Type:    "MessageCard",
+		Context: "https://schema.org/extensions",
+		Summary: msg.Text,
+		Text:    msg.Text,
+	}
+	if len(msg.Attachments) == 0 {
+		return card
+	}
+	attachment := msg.Attachments[0]
+	if len(attachment.Fallback) > 0 {
+		card.Summary = attachment.Fallback
+	}
+	if len(attachment.Text) > 0 {
+		card.Text = attachment.Text
+	}
+	if strings.HasPrefix(attachment.Color, "#") {
+		card.ThemeColor = strings.TrimPrefix(attachment.Color, "#")
+	}
+	if len(attachment.Fields) > 0 || len(attachment.Footer) > 0 {
+		section := teamsSection{Text: attachment.Footer}
+		for _, field := range attachment.Fields {
+			section.Facts = append(section.Facts, teamsFact{Name: field.Title, Value: field.Value})
+		}
+		card.Sections = append(card.Sections, section)
+	}
+	for _, action := range attachment.Actions {
+		if len(action.URL) == 0 {
+			continue
+		}
+		card.PotentialAction = append(card.PotentialAction, teamsAction{
+			Type:    "OpenUri",
+			Name:    action.Text,
+			Targets: []teamsTarget{{OS: "default", URI: action.URL}},
+		})
+	}
+	return card
+}
+
+// postRelay posts the payload to a Discord or Teams webhook, which accept
+// any 2xx response as delivered.
+func postRelay(ctx context.Context, webhook string, client *http.Client, payload []byte) error {
+	resp, _, err := postJSON(ctx, webhook, client, payload)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// deliverToRelay sends the message to the Discord or Teams target, retrying
+// as configured. Discord takes the Slack payload as it is on its /slack
+// endpoint, while Teams gets the message as a message card.
+func deliverToRelay(ctx context.Context, event *corev2.Event, target string, hookmsg *slack.WebhookMessage) error {
+	var webhook string
+	var payload []byte
+	var err error
+	switch target {
+	case "discord":
+		webhook = strings.TrimSuffix(config.discordWebhookURL, "/") + "/slack"
+		payload, err = marshalMessage(hookmsg)
+	case "teams":
+		webhook = config.teamsWebhookURL
+		payload, err = json.Marshal(teamsCard(hookmsg))
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to marshal %s message: %v", target, err)
+	}
+	backoff, maxRetryAfter, err := retryDurations()
+	if err != nil {
+		return err
+	}
+	client := newHTTPClient(event)
+	attempts, err := withRetries(ctx, backoff, maxRetryAfter, func() error {
+		return postRelay(ctx, webhook, client, payload)
+	})
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &maxRuntimeError{action: fmt.Sprintf("sending %s message", target)}
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to send %s message after %d attempt(s): %v", target, attempts, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendMessageRelayTargets(t *testing.T) {
+	assert := assert.New(t)
+	var discordPath string
+	var discordBody, teamsBody map[string]interface{}
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		discordPath = r.URL.Path
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&discordBody))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer discord.Close()
+	teams := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&teamsBody))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer teams.Close()
+
+	config.discordWebhookURL = discord.URL + "/api/webhooks/123/abc"
+	config.teamsWebhookURL = teams.URL + "/webhookb2/abc"
+	config.slackDescriptionTemplate = "{{ .Check.Name }} on {{ .Entity.Name }}"
+	config.targets = []string{"discord", "teams"}
+	defer func() {
+		config.discordWebhookURL = ""
+		config.teamsWebhookURL = ""
+		config.slackDescriptionTemplate = ""
+		config.targets = nil
+	}()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+	var err error
+	out := captureStdout(t, func() { err = sendMessage(event) })
+	require.NoError(t, err)
+	assert.Contains(out, "Notification sent to discord")
+	assert.Contains(out, "Notification sent to teams")
+
+	assert.Equal("/api/webhooks/123/abc/slack", discordPath)
+	attachments := discordBody["attachments"].([]interface{})
+	assert.Equal("check1 on entity1", attachments[0].(map[string]interface{})["text"])
+
+	assert.Equal("MessageCard", teamsBody["@type"])
+	assert.Equal("check1 on entity1", teamsBody["text"])
+	assert.Equal("ff0000", teamsBody["themeColor"])
+	actions := teamsBody["potentialAction"].([]interface{})
+	assert.Equal("View in Sensu", actions[0].(map[string]interface{})["name"])
+
+	// A failing relay is reported without its webhook URL
+	teams.Config.Handler = http.NotFoundHandler()
+	out = captureStdout(t, func() { err = sendMessage(event) })
+	assert.ErrorContains(err, "Failed to send teams message")
+	assert.NotContains(out, "webhookb2")
+}
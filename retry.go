@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/slack-go/slack"
+	"math/rand/v2"
+	"time"
+)
+
+// sleep pauses for d or until ctx is done, and is replaced in tests.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isRetryable reports whether a failed delivery may succeed if it is retried,
+// such as after a rate limit (429) or server error (5xx) response.
+func isRetryable(err error) bool {
+	var r interface{ Retryable() bool }
+	return errors.As(err, &r) && r.Retryable()
+}
+
+// retryRand is the source of retry jitter, and is replaced with a fixed seed
+// in tests
+var retryRand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+
+// retryDelay returns how long to wait before the given retry, doubling the
+// backoff for each retry, with jitter as configured so that handler
+// processes retrying together spread out. A longer Retry-After from Slack is
+// honoured.
+func retryDelay(backoff time.Duration, retry int, err error) time.Duration {
+	delay := jitter(backoff << (retry - 1))
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) && rateLimited.RetryAfter > delay {
+		delay = rateLimited.RetryAfter
+	}
+	return delay
+}
+
+// jitter randomizes the delay according to --retry-jitter: "additive" adds up
+// to half again, "full" picks anything up to the delay, "equal" keeps half of
+// it and picks the rest, and "none" leaves it as it is.
+func jitter(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return delay
+	}
+	switch config.retryJitter {
+	case "none":
+		return delay
+	case "full":
+		return randomDuration(delay)
+	case "equal":
+		return delay/2 + randomDuration(delay-delay/2)
+	default:
+		return delay + randomDuration(delay/2)
+	}
+}
+
+// randomDuration returns a random duration from 0 up to and including max.
+func randomDuration(max time.Duration) time.Duration {
+	return time.Duration(retryRand.Int64N(int64(max) + 1))
+}
+
+// withRetries calls send until it succeeds, fails with an error that isn't
+// retryable, or the configured number of retries is used up. Retrying also
+// stops if Slack asks for a longer wait than maxRetryAfter, when it is set.
+// It returns the number of attempts made along with the last error.
+func withRetries(ctx context.Context, backoff, maxRetryAfter time.Duration, send func() error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || !isRetryable(err) || attempt > config.maxRetries {
+			return attempt, err
+		}
+		var rateLimited *slack.RateLimitedError
+		if maxRetryAfter > 0 && errors.As(err, &rateLimited) && rateLimited.RetryAfter > maxRetryAfter {
+			return attempt, fmt.Errorf("%v, which is longer than --%s of %s", err, retryAfterMax, maxRetryAfter)
+		}
+		delay := retryDelay(backoff, attempt, err)
+		logf("Attempt %d to send Slack message failed: %v, retrying in %s\n", attempt, err, delay)
+		if err := sleep(ctx, delay); err != nil {
+			return attempt, err
+		}
+	}
+}
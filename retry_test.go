@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// stubRetries serves the given status codes in turn, records the delays the
+// handler sleeps for, and returns the number of requests made.
+func stubRetries(t *testing.T, codes ...int) (*int, *[]time.Duration) {
+	t.Helper()
+	requests := 0
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := codes[len(codes)-1]
+		if requests < len(codes) {
+			code = codes[requests]
+		}
+		requests++
+		if code == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "2")
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(apiStub.Close)
+
+	var delays []time.Duration
+	savedSleep := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	config.slackwebHookURL = apiStub.URL
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	config.maxRetries = 3
+	config.retryBackoff = "500ms"
+	t.Cleanup(func() {
+		sleep = savedSleep
+		config.maxRetries = 0
+		config.retryBackoff = ""
+	})
+	return &requests, &delays
+}
+
+func TestSendMessageRetries(t *testing.T) {
+	assert := assert.New(t)
+	requests, delays := stubRetries(t, http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK)
+
+	assert.NoError(sendMessage(corev2.FixtureEvent("entity1", "check1")))
+	assert.Equal(3, *requests)
+	if assert.Len(*delays, 2) {
+		// exponential backoff with up to 50% jitter
+		assert.GreaterOrEqual((*delays)[0], 500*time.Millisecond)
+		assert.LessOrEqual((*delays)[0], 750*time.Millisecond)
+		assert.GreaterOrEqual((*delays)[1], time.Second)
+		assert.LessOrEqual((*delays)[1], 1500*time.Millisecond)
+	}
+}
+
+func TestSendMessageRetriesExhausted(t *testing.T) {
+	assert := assert.New(t)
+	requests, delays := stubRetries(t, http.StatusBadGateway)
+
+	err := sendMessage(corev2.FixtureEvent("entity1", "check1"))
+	assert.ErrorContains(err, "after 4 attempt(s)")
+	assert.Equal(4, *requests)
+	assert.Len(*delays, 3)
+}
+
+func TestSendMessageNotRetryable(t *testing.T) {
+	assert := assert.New(t)
+	for _, code := range []int{http.StatusBadRequest, http.StatusNotFound} {
+		requests, delays := stubRetries(t, code)
+		err := sendMessage(corev2.FixtureEvent("entity1", "check1"))
+		assert.ErrorContains(err, "after 1 attempt(s)")
+		assert.Equal(1, *requests)
+		assert.Empty(*delays)
+	}
+}
+
+func TestSendMessageRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	_, delays := stubRetries(t, http.StatusTooManyRequests, http.StatusOK)
+
+	assert.NoError(sendMessage(corev2.FixtureEvent("entity1", "check1")))
+	assert.Equal([]time.Duration{2 * time.Second}, *delays)
+}
+
+func TestSendMessageRetryAfterMissing(t *testing.T) {
+	assert := assert.New(t)
+	_, delays := stubRetries(t, http.StatusOK)
+	requests := 0
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("Retry-After", now().Add(3*time.Second).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer apiStub.Close()
+	config.slackwebHookURL = apiStub.URL
+	config.retryJitter = "none"
+	defer func() { config.retryJitter = "" }()
+
+	// Without a usable Retry-After, the usual backoff applies
+	assert.NoError(sendMessage(corev2.FixtureEvent("entity1", "check1")))
+	assert.Equal(3, requests)
+	if assert.Len(*delays, 2) {
+		assert.Equal(500*time.Millisecond, (*delays)[0])
+		assert.Greater((*delays)[1], 2*time.Second)
+		assert.LessOrEqual((*delays)[1], 3*time.Second)
+	}
+}
+
+func TestSendMessageRetryAfterMax(t *testing.T) {
+	assert := assert.New(t)
+	requests, delays := stubRetries(t, http.StatusTooManyRequests, http.StatusOK)
+	config.retryAfterMax = "1s"
+	defer func() { config.retryAfterMax = "" }()
+
+	err := sendMessage(corev2.FixtureEvent("entity1", "check1"))
+	assert.ErrorContains(err, "retry after 2s, which is longer than --retry-after-max of 1s")
+	assert.ErrorContains(err, "after 1 attempt(s)")
+	assert.Equal(1, *requests)
+	assert.Empty(*delays)
+}
+
+func TestRetryJitter(t *testing.T) {
+	assert := assert.New(t)
+	savedRand := retryRand
+	retryRand = rand.New(rand.NewPCG(1, 2))
+	defer func() {
+		retryRand = savedRand
+		config.retryJitter = ""
+	}()
+
+	ranges := map[string][2]time.Duration{
+		"additive": {time.Second, 1500 * time.Millisecond},
+		"full":     {0, time.Second},
+		"equal":    {500 * time.Millisecond, time.Second},
+		"none":     {time.Second, time.Second},
+	}
+	for mode, bounds := range ranges {
+		config.retryJitter = mode
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 100; i++ {
+			// the second retry doubles the 500ms backoff
+			delay := retryDelay(500*time.Millisecond, 2, nil)
+			assert.GreaterOrEqual(delay, bounds[0], mode)
+			assert.LessOrEqual(delay, bounds[1], mode)
+			seen[delay] = true
+		}
+		if mode == "none" {
+			assert.Len(seen, 1)
+		} else {
+			assert.Greater(len(seen), 50, mode)
+		}
+	}
+
+	// The same seed gives the same delays, so tests can rely on them
+	config.retryJitter = "full"
+	retryRand = rand.New(rand.NewPCG(1, 2))
+	first := retryDelay(500*time.Millisecond, 1, nil)
+	retryRand = rand.New(rand.NewPCG(1, 2))
+	assert.Equal(first, retryDelay(500*time.Millisecond, 1, nil))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// channelIDPattern matches Slack conversation IDs, such as C0123ABCD.
+var channelIDPattern = regexp.MustCompile(`^[CGD][A-Z0-9]{8,}$`)
+
+// mentionIDPattern matches Slack user and user group IDs, such as U0123ABCD
+// and S0456EFGH, that may be given bare to --mention-on-critical.
+var mentionIDPattern = regexp.MustCompile(`^[SUW][A-Z0-9]{8,}$`)
+
+// resolveChannel returns the channel an event should be posted to.
+func resolveChannel(event *corev2.Event) string {
+	channel := severityChannel(event)
+	if label := labelValue(event, config.channelLabel); len(label) > 0 {
+		channel = label
+	}
+	if len(config.slackFlapChannel) > 0 && exceedsFlapThreshold(event) {
+		channel = config.slackFlapChannel
+	}
+	if config.normalizeChannelNames {
+		channel = normalizeChannel(channel)
+	}
+	if len(strings.TrimSpace(channel)) == 0 {
+		channel = config.slackFallbackChannel
+	}
+	return channel
+}
+
+// severityChannel returns the channel configured for the event's check
+// status, or the default channel if there isn't one.
+func severityChannel(event *corev2.Event) string {
+	var channel string
+	switch event.Check.Status {
+	case 0:
+		channel = config.slackOKChannel
+	case 1:
+		channel = config.slackWarningChannel
+	case 2:
+		channel = config.slackCriticalChannel
+	}
+	if len(channel) == 0 {
+		return config.slackChannel
+	}
+	return channel
+}
+
+// labelValue returns the named label from the check, or else from the entity,
+// or an empty string if neither has it.
+func labelValue(event *corev2.Event, name string) string {
+	if len(name) == 0 {
+		return ""
+	}
+	if value := strings.TrimSpace(event.Check.Labels[name]); len(value) > 0 {
+		return value
+	}
+	return strings.TrimSpace(event.Entity.Labels[name])
+}
+
+// normalizeChannel lowercases a channel name, strips any whitespace and
+// ensures it has a leading #. Channel IDs and user (@) targets are returned
+// without whitespace but otherwise unchanged.
+func normalizeChannel(channel string) string {
+	channel = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, channel)
+	if len(channel) == 0 || channelIDPattern.MatchString(channel) || strings.HasPrefix(channel, "@") {
+		return channel
+	}
+	return "#" + strings.TrimPrefix(strings.ToLower(channel), "#")
+}
+
+// exceedsFlapThreshold reports whether the check's total state change is at
+// or above the configured flap threshold, or the check's own high flap
+// threshold when none is configured.
+func exceedsFlapThreshold(event *corev2.Event) bool {
+	threshold := config.flapPercentThreshold
+	if threshold == 0 {
+		threshold = event.Check.HighFlapThreshold
+	}
+	return threshold > 0 && event.Check.TotalStateChange >= threshold
+}
+
+// resolveWebhookURL returns the webhook URL to post to the given channel,
+// preferring a channel-specific webhook over the default one.
+func resolveWebhookURL(channel string) string {
+	if url, ok := config.channelWebhookURLs[channel]; ok && len(url) > 0 {
+		return url
+	}
+	for name, url := range config.channelWebhookURLs {
+		if len(url) > 0 && strings.EqualFold(strings.TrimPrefix(name, "#"), strings.TrimPrefix(channel, "#")) {
+			return url
+		}
+	}
+	return config.slackwebHookURL
+}
+
+// webhookURLs splits a comma-separated list of webhook URLs, such as the
+// primary and fallback webhooks given to --webhook-url, into its entries.
+func webhookURLs(list string) []string {
+	var urls []string
+	for _, url := range strings.Split(list, ",") {
+		if url = strings.TrimSpace(url); len(url) > 0 {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
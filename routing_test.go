@@ -0,0 +1,174 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveChannelFlapping(t *testing.T) {
+	assert := assert.New(t)
+	config.slackChannel = "#alerts"
+	config.slackFlapChannel = "#flapping"
+	config.flapPercentThreshold = 30
+	defer func() {
+		config.slackFlapChannel = ""
+		config.flapPercentThreshold = 0
+	}()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.TotalStateChange = 10
+	assert.Equal("#alerts", resolveChannel(event))
+
+	event.Check.TotalStateChange = 45
+	assert.Equal("#flapping", resolveChannel(event))
+
+	// Without an explicit threshold, the check's own threshold applies
+	config.flapPercentThreshold = 0
+	event.Check.HighFlapThreshold = 50
+	assert.Equal("#alerts", resolveChannel(event))
+	event.Check.TotalStateChange = 60
+	assert.Equal("#flapping", resolveChannel(event))
+
+	// No flap channel, no flap routing
+	config.slackFlapChannel = ""
+	assert.Equal("#alerts", resolveChannel(event))
+}
+
+func TestResolveChannelLabel(t *testing.T) {
+	assert := assert.New(t)
+	config.slackChannel = "#alerts"
+	config.channelLabel = "slack_channel"
+	defer func() { config.channelLabel = "" }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	assert.Equal("#alerts", resolveChannel(event))
+
+	event.Entity.Labels = map[string]string{"slack_channel": "#team-db"}
+	assert.Equal("#team-db", resolveChannel(event))
+
+	event.Check.Labels = map[string]string{"slack_channel": "#team-web"}
+	assert.Equal("#team-web", resolveChannel(event))
+
+	config.channelLabel = ""
+	assert.Equal("#alerts", resolveChannel(event))
+}
+
+func TestResolveChannelSeverity(t *testing.T) {
+	assert := assert.New(t)
+	config.slackChannel = "#alerts"
+	config.slackCriticalChannel = "#on-call"
+	config.slackWarningChannel = "#quiet"
+	defer func() {
+		config.slackCriticalChannel = ""
+		config.slackWarningChannel = ""
+		config.channelLabel = ""
+	}()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+	assert.Equal("#on-call", resolveChannel(event))
+	event.Check.Status = 1
+	assert.Equal("#quiet", resolveChannel(event))
+
+	// unconfigured severities use the default channel
+	event.Check.Status = 0
+	assert.Equal("#alerts", resolveChannel(event))
+	event.Check.Status = 3
+	assert.Equal("#alerts", resolveChannel(event))
+
+	// a channel label takes precedence over severity routing
+	config.channelLabel = "slack_channel"
+	event.Check.Status = 2
+	event.Entity.Labels = map[string]string{"slack_channel": "#team-db"}
+	assert.Equal("#team-db", resolveChannel(event))
+}
+
+func TestNormalizeChannel(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("#general", normalizeChannel("General"))
+	assert.Equal("#general", normalizeChannel("#general"))
+	assert.Equal("#general", normalizeChannel(" #General "))
+	assert.Equal("#ops-alerts", normalizeChannel("ops- alerts"))
+	assert.Equal("C0123ABCD", normalizeChannel("C0123ABCD"))
+	assert.Equal("G01ABCDEFGH", normalizeChannel("G01ABCDEFGH"))
+	assert.Equal("@someone", normalizeChannel("@someone"))
+}
+
+func TestResolveChannelNormalization(t *testing.T) {
+	assert := assert.New(t)
+	config.slackChannel = "General"
+	defer func() { config.normalizeChannelNames = false }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	config.normalizeChannelNames = false
+	assert.Equal("General", resolveChannel(event))
+	config.normalizeChannelNames = true
+	assert.Equal("#general", resolveChannel(event))
+}
+
+func TestResolveChannelFallback(t *testing.T) {
+	assert := assert.New(t)
+	config.slackChannel = ""
+	config.slackFlapChannel = "#flapping"
+	config.flapPercentThreshold = 50
+	config.slackFallbackChannel = "#catch-all"
+	defer func() {
+		config.slackChannel = ""
+		config.slackFlapChannel = ""
+		config.flapPercentThreshold = 0
+		config.slackFallbackChannel = ""
+	}()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.TotalStateChange = 10
+	assert.Equal("#catch-all", resolveChannel(event))
+
+	config.slackChannel = "  "
+	config.normalizeChannelNames = true
+	assert.Equal("#catch-all", resolveChannel(event))
+	config.normalizeChannelNames = false
+
+	config.slackChannel = "#alerts"
+	assert.Equal("#alerts", resolveChannel(event))
+}
+
+func TestResolveWebhookURL(t *testing.T) {
+	assert := assert.New(t)
+	config.slackwebHookURL = "https://hooks.example.com/default"
+	config.channelWebhookURLs = map[string]string{
+		"#alerts": "https://hooks.example.com/alerts",
+		"ops":     "https://hooks.example.com/ops",
+	}
+	defer func() { config.channelWebhookURLs = nil }()
+
+	assert.Equal("https://hooks.example.com/alerts", resolveWebhookURL("#alerts"))
+	assert.Equal("https://hooks.example.com/ops", resolveWebhookURL("#ops"))
+	assert.Equal("https://hooks.example.com/ops", resolveWebhookURL("#OPS"))
+	assert.Equal("https://hooks.example.com/default", resolveWebhookURL("#general"))
+}
+
+func TestSendMessageChannelWebhook(t *testing.T) {
+	assert := assert.New(t)
+	var hits []string
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits = append(hits, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiStub.Close()
+
+	config.slackwebHookURL = apiStub.URL + "/default"
+	config.channelWebhookURLs = map[string]string{"#alerts": apiStub.URL + "/alerts"}
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	defer func() { config.channelWebhookURLs = nil }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	config.slackChannel = "#alerts"
+	assert.NoError(sendMessage(event))
+	config.slackChannel = "#general"
+	assert.NoError(sendMessage(event))
+
+	assert.Equal([]string{"/alerts", "/default"}, hits)
+}
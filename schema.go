@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/slack-go/slack"
+)
+
+// legacyOmittedFields are the message and attachment fields left out of the
+// legacy schema, which older Slack-compatible receivers reject.
+var legacyOmittedFields = []string{"blocks", "mrkdwn_in"}
+
+// marshalMessage returns the message as JSON in the configured schema.
+// slack-go always emits an attachment's blocks, even when there are none, so
+// the legacy schema removes the newer fields after encoding.
+func marshalMessage(msg *slack.WebhookMessage) ([]byte, error) {
+	raw, err := json.Marshal(msg)
+	if err != nil || config.schemaVersion != "legacy" {
+		return raw, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	omitLegacyFields(doc)
+	if attachments, ok := doc["attachments"].([]interface{}); ok {
+		for _, attachment := range attachments {
+			if fields, ok := attachment.(map[string]interface{}); ok {
+				omitLegacyFields(fields)
+			}
+		}
+	}
+	return json.Marshal(doc)
+}
+
+func omitLegacyFields(fields map[string]interface{}) {
+	for _, name := range legacyOmittedFields {
+		delete(fields, name)
+	}
+}
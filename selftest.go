@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// selfTest checks that Slack accepts the configured credentials before any
+// message is sent, so that misconfiguration fails fast with a clear error.
+func selfTest(ctx context.Context, event *corev2.Event) error {
+	if len(config.slackAPIToken) > 0 {
+		return selfTestAPI(ctx, event)
+	}
+	// Fallback webhooks exist for when others fail, so one usable webhook
+	// is enough. The webhooks tested are the ones the event will be sent to,
+	// which may be a channel-specific webhook.
+	var errs []error
+	for _, webhook := range webhookURLs(resolveWebhookURL(selfTestChannel(event))) {
+		err := selfTestWebhook(ctx, event, webhook)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// selfTestChannel returns the channel the event will be sent to, or the
+// default channel for events without an entity or check, which can't be
+// routed.
+func selfTestChannel(event *corev2.Event) string {
+	if event.Entity == nil || event.Check == nil {
+		return config.slackChannel
+	}
+	return resolveChannel(event)
+}
+
+// selfTestAPI checks the bot token with auth.test and, when the channel the
+// event will be sent to is given as an ID, that the bot can see the channel.
+func selfTestAPI(ctx context.Context, event *corev2.Event) error {
+	api := newAPIClient(event)
+	if _, err := api.AuthTestContext(ctx); err != nil {
+		return fmt.Errorf("Slack rejected the API token: %v", err)
+	}
+	if channel := selfTestChannel(event); channelIDPattern.MatchString(channel) {
+		input := &slack.GetConversationInfoInput{ChannelID: channel}
+		if _, err := api.GetConversationInfoContext(ctx, input); err != nil {
+			return fmt.Errorf("cannot access Slack channel %s: %v", channel, err)
+		}
+	}
+	return nil
+}
+
+// selfTestWebhook posts an empty message to the webhook. Slack rejects it
+// with a 400 if the webhook is valid, and with a 403, 404 or 410 if the
+// webhook or its channel is no longer usable, so nothing is posted either way.
+func selfTestWebhook(ctx context.Context, event *corev2.Event, webhook string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader([]byte("{}")))
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %v", redactURLError(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := newHTTPClient(event).Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot reach the Slack webhook: %v", redactURLError(err))
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusBadRequest:
+		return nil
+	default:
+		return fmt.Errorf("Slack rejected the webhook: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+)
+
+// eventState is the information persisted between handler invocations for a
+// single event key, or for a mention key.
+type eventState struct {
+	// LastStatus is the check status of the last notification sent.
+	LastStatus *uint32 `json:"last_status,omitempty"`
+	// LastNotified is the Unix time at which the last notification was sent.
+	LastNotified int64 `json:"last_notified,omitempty"`
+	// LastMentioned is the Unix time at which the mention was last sent.
+	LastMentioned int64 `json:"last_mentioned,omitempty"`
+	// ThreadChannel and ThreadTS identify the alert message that later
+	// notifications for the event are threaded under, or updated in place.
+	ThreadChannel string `json:"thread_channel,omitempty"`
+	ThreadTS      string `json:"thread_ts,omitempty"`
+	// ThreadAlerts lists the events with an unresolved alert in a thread
+	// shared by a label, so that the thread ends when the last one resolves.
+	ThreadAlerts []string `json:"thread_alerts,omitempty"`
+	// InFlightStatus is the check status a handler has claimed the delivery
+	// of, and InFlightSince the Unix time at which it did, so that handlers
+	// running at the same time don't both deliver it.
+	InFlightStatus *uint32 `json:"in_flight_status,omitempty"`
+	InFlightSince  int64   `json:"in_flight_since,omitempty"`
+}
+
+// stateStore persists eventState records keyed by stateKey, threadKey or
+// mentionKey.
+type stateStore interface {
+	Get(key string) (eventState, bool, error)
+	Set(key string, state eventState) error
+	// Update replaces the state stored for key with the result of update,
+	// without any other update happening in between.
+	Update(key string, update func(state eventState) eventState) error
+}
+
+// stateLockTimeout is how long to wait for another handler process to
+// release the state file, and is replaced in tests
+var stateLockTimeout = 10 * time.Second
+
+// inFlightTimeout is how long a claimed delivery blocks other handlers, after
+// which the handler that claimed it is assumed to have died.
+const inFlightTimeout = 5 * time.Minute
+
+// stateDocument is the on-disk representation of the state file.
+type stateDocument struct {
+	Events map[string]eventState `json:"events"`
+}
+
+// fileStateStore is a stateStore backed by a JSON document on disk.
+type fileStateStore struct {
+	path string
+	// ctx, if set, stops waiting for the lock when it's done
+	ctx context.Context
+}
+
+// newStateStore returns the configured state store, or nil if no state file
+// has been configured. Waiting for the state file's lock gives up once ctx
+// is done.
+func newStateStore(ctx context.Context) stateStore {
+	if len(config.stateFile) == 0 {
+		return nil
+	}
+	return &fileStateStore{path: config.stateFile, ctx: ctx}
+}
+
+func (s *fileStateStore) Get(key string) (eventState, bool, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return eventState{}, false, err
+	}
+	defer unlock()
+	doc, err := s.load()
+	if err != nil {
+		return eventState{}, false, err
+	}
+	state, ok := doc.Events[key]
+	return state, ok, nil
+}
+
+func (s *fileStateStore) Set(key string, state eventState) error {
+	return s.Update(key, func(eventState) eventState { return state })
+}
+
+func (s *fileStateStore) Update(key string, update func(state eventState) eventState) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	doc, err := s.load()
+	if err != nil {
+		return err
+	}
+	doc.Events[key] = update(doc.Events[key])
+	return s.save(doc)
+}
+
+// lock takes an advisory lock shared by every handler process using the state
+// file, waiting up to stateLockTimeout, or until the store's context is done,
+// for other processes to release it. The lock is held on a separate file,
+// because save replaces the state file.
+func (s *fileStateStore) lock() (func(), error) {
+	f, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock state file: %v", err)
+	}
+	var done <-chan struct{}
+	if s.ctx != nil {
+		done = s.ctx.Done()
+	}
+	deadline := time.Now().Add(stateLockTimeout)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to lock state file: %v", err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			_ = f.Close()
+			return nil, fmt.Errorf("timed out after %s waiting for the lock on state file %s", stateLockTimeout, s.path)
+		}
+		select {
+		case <-done:
+			_ = f.Close()
+			return nil, fmt.Errorf("gave up waiting for the lock on state file %s: %w", s.path, s.ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
+
+func (s *fileStateStore) load() (stateDocument, error) {
+	doc := stateDocument{Events: map[string]eventState{}}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return doc, nil
+	}
+	if err != nil {
+		return doc, fmt.Errorf("failed to read state file: %v", err)
+	}
+	if len(data) == 0 {
+		return doc, nil
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return doc, fmt.Errorf("failed to parse state file %s: %v", s.path, err)
+	}
+	if doc.Events == nil {
+		doc.Events = map[string]eventState{}
+	}
+	return doc, nil
+}
+
+// save writes the document to a temporary file and renames it into place so
+// that readers never observe a partially written state file.
+func (s *fileStateStore) save(doc stateDocument) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	return nil
+}
+
+// claimDelivery claims the delivery of the notification for the event's
+// current status, and reports whether it did. The claim fails if the status
+// was already notified, or if another handler is delivering it. The check
+// and the claim are a single update, so only one handler gets the claim.
+func claimDelivery(store stateStore, event *corev2.Event) (bool, error) {
+	status := event.Check.Status
+	claimed := false
+	err := store.Update(stateKey(event), func(state eventState) eventState {
+		if state.LastStatus != nil && *state.LastStatus == status {
+			return state
+		}
+		if state.InFlightStatus != nil && *state.InFlightStatus == status && now().Sub(time.Unix(state.InFlightSince, 0)) < inFlightTimeout {
+			return state
+		}
+		state.InFlightStatus = &status
+		state.InFlightSince = now().Unix()
+		claimed = true
+		return state
+	})
+	return claimed && err == nil, err
+}
+
+// releaseDelivery drops the claim on the delivery of the event's current
+// status, if it is still held, so that the next handler can try again.
+func releaseDelivery(store stateStore, event *corev2.Event) error {
+	status := event.Check.Status
+	return store.Update(stateKey(event), func(state eventState) eventState {
+		if state.InFlightStatus != nil && *state.InFlightStatus == status {
+			state.InFlightStatus = nil
+			state.InFlightSince = 0
+		}
+		return state
+	})
+}
+
+// recordNotification updates the stored state after a notification for the
+// event has been delivered.
+func recordNotification(store stateStore, event *corev2.Event) error {
+	return store.Update(stateKey(event), func(state eventState) eventState {
+		status := event.Check.Status
+		state.LastStatus = &status
+		state.LastNotified = now().Unix()
+		state.InFlightStatus = nil
+		state.InFlightSince = 0
+		return state
+	})
+}
+
+// sinceLastNotification describes how long ago the last notification for the
+// event was sent, or returns "first time" if none has been recorded.
+func sinceLastNotification(store stateStore, event *corev2.Event) (string, error) {
+	if store == nil {
+		return "first time", nil
+	}
+	state, _, err := store.Get(stateKey(event))
+	if err != nil || state.LastNotified == 0 {
+		return "first time", err
+	}
+	return now().Sub(time.Unix(state.LastNotified, 0)).Round(time.Second).String(), nil
+}
+
+// stateKey returns the state key of the event: its namespace, entity and
+// check, since the same entity and check names can be used in more than one
+// namespace.
+func stateKey(event *corev2.Event) string {
+	return event.Entity.Namespace + "/" + eventKey(event)
+}
+
+// mentionKey returns the state key tracking a mention in a channel.
+func mentionKey(channel, mention string) string {
+	return "mention:" + channel + "/" + mention
+}
+
+// mentionedWithin reports whether the mention was sent to the channel less
+// than window ago.
+func mentionedWithin(store stateStore, channel, mention string, window time.Duration) (bool, error) {
+	state, ok, err := store.Get(mentionKey(channel, mention))
+	if err != nil || !ok || state.LastMentioned == 0 {
+		return false, err
+	}
+	return now().Sub(time.Unix(state.LastMentioned, 0)) < window, nil
+}
+
+// recordMention records that the mention was sent to the channel now.
+func recordMention(store stateStore, channel, mention string) error {
+	return store.Set(mentionKey(channel, mention), eventState{LastMentioned: now().Unix()})
+}
+
+// threadKey returns the state key of the thread the event's notifications
+// belong to, and whether that thread is shared by all events with the same
+// value of the --thread-by-label label. Events without the label are threaded
+// by their state key.
+func threadKey(event *corev2.Event) (string, bool) {
+	if len(config.threadByLabel) > 0 {
+		value := event.Check.Labels[config.threadByLabel]
+		if len(value) == 0 {
+			value = event.Entity.Labels[config.threadByLabel]
+		}
+		if len(value) > 0 {
+			return "label:" + config.threadByLabel + "=" + value, true
+		}
+	}
+	return stateKey(event), false
+}
+
+// alertThread returns the channel and timestamp of the alert message the
+// event's notification should be threaded under, if one was recorded.
+func alertThread(store stateStore, event *corev2.Event) (string, string, error) {
+	key, _ := threadKey(event)
+	state, _, err := store.Get(key)
+	if err != nil {
+		return "", "", err
+	}
+	return state.ThreadChannel, state.ThreadTS, nil
+}
+
+// recordThread updates the alert message the event's notifications are
+// threaded under after a message has been posted at ts. A resolution clears
+// it, so that the next alert starts a new thread; otherwise the first alert's
+// message is kept. A thread shared by a label is only cleared once every
+// event alerting in it has resolved. When updating messages in place, the
+// latest message is always the one recorded.
+func recordThread(store stateStore, event *corev2.Event, channel, ts string) error {
+	key, shared := threadKey(event)
+	return store.Update(key, func(state eventState) eventState {
+		switch {
+		case config.updateInPlace:
+			state.ThreadChannel, state.ThreadTS = channel, ts
+		case shared:
+			member := stateKey(event)
+			state.ThreadAlerts = slices.DeleteFunc(state.ThreadAlerts, func(m string) bool { return m == member })
+			if event.Check.Status != 0 {
+				state.ThreadAlerts = append(state.ThreadAlerts, member)
+				if len(state.ThreadTS) == 0 {
+					state.ThreadChannel, state.ThreadTS = channel, ts
+				}
+			}
+			if len(state.ThreadAlerts) == 0 {
+				state.ThreadChannel, state.ThreadTS = "", ""
+			}
+		case event.Check.Status == 0:
+			state.ThreadChannel, state.ThreadTS = "", ""
+		case len(state.ThreadTS) == 0:
+			state.ThreadChannel, state.ThreadTS = channel, ts
+		}
+		return state
+	})
+}
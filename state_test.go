@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	corev2 "github.com/sensu/core/v2"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileStateStore(t *testing.T) {
+	assert := assert.New(t)
+	store := &fileStateStore{path: filepath.Join(t.TempDir(), "state.json")}
+
+	_, ok, err := store.Get("entity1/check1")
+	assert.NoError(err)
+	assert.False(ok)
+
+	status := uint32(2)
+	assert.NoError(store.Set("entity1/check1", eventState{LastStatus: &status}))
+
+	state, ok, err := store.Get("entity1/check1")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(uint32(2), *state.LastStatus)
+}
+
+func TestFileStateStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0600))
+	store := &fileStateStore{path: path}
+	_, _, err := store.Get("entity1/check1")
+	assert.Error(t, err)
+}
+
+func TestFileStateStoreConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	// Each writer has its own store, as separate handler processes would,
+	// and records events for its own entity. Without locking, writers
+	// overwrite each other's updates.
+	const writers, checks = 10, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*checks)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store := &fileStateStore{path: path}
+			for j := 0; j < checks; j++ {
+				event := corev2.FixtureEvent(fmt.Sprintf("entity%d", i), fmt.Sprintf("check%d", j))
+				errs <- recordNotification(store, event)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var doc stateDocument
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, writers*checks, len(doc.Events), "updates were lost")
+}
+
+func TestFileStateStoreLockTimeout(t *testing.T) {
+	store := &fileStateStore{path: filepath.Join(t.TempDir(), "state.json")}
+	unlock, err := store.lock()
+	require.NoError(t, err)
+	defer unlock()
+
+	stateLockTimeout = 50 * time.Millisecond
+	defer func() { stateLockTimeout = 10 * time.Second }()
+	_, _, err = (&fileStateStore{path: store.path}).Get("entity1/check1")
+	assert.ErrorContains(t, err, "timed out")
+}
+
+func TestSendMessageMaxRuntimeStateLock(t *testing.T) {
+	assert := assert.New(t)
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiStub.Close()
+
+	config.slackwebHookURL = apiStub.URL
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	config.stateFile = filepath.Join(t.TempDir(), "state.json")
+	config.deliverOncePerStatus = true
+	config.maxRuntime = "50ms"
+	defer func() {
+		config.stateFile = ""
+		config.deliverOncePerStatus = false
+		config.maxRuntime = ""
+	}()
+
+	// Another handler holding the lock doesn't keep this one waiting past
+	// --max-runtime
+	unlock, err := (&fileStateStore{path: config.stateFile}).lock()
+	require.NoError(t, err)
+	defer unlock()
+
+	start := time.Now()
+	err = sendMessage(corev2.FixtureEvent("entity1", "check1"))
+	assert.Less(time.Since(start), 2*time.Second)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "exceeded max runtime of 50ms")
+		assert.Contains(err.Error(), "lock on state file")
+	}
+}
+
+func TestDeliverOncePerKeyPerStatus(t *testing.T) {
+	assert := assert.New(t)
+	posts := 0
+	apiStub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiStub.Close()
+
+	config.slackwebHookURL = apiStub.URL
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	config.stateFile = filepath.Join(t.TempDir(), "state.json")
+	config.deliverOncePerStatus = true
+	defer func() {
+		config.stateFile = ""
+		config.deliverOncePerStatus = false
+	}()
+
+	// Each call to sendMessage opens the state file afresh, as a new
+	// handler process would.
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+	assert.NoError(sendMessage(event))
+	assert.Equal(1, posts)
+
+	assert.NoError(sendMessage(event))
+	assert.Equal(1, posts)
+
+	event.Check.Status = 0
+	assert.NoError(sendMessage(event))
+	assert.Equal(2, posts)
+
+	assert.NoError(sendMessage(event))
+	assert.Equal(2, posts)
+
+	other := corev2.FixtureEvent("entity2", "check1")
+	other.Check.Status = 0
+	assert.NoError(sendMessage(other))
+	assert.Equal(3, posts)
+
+	// The same entity and check in another namespace has its own state
+	staging := corev2.FixtureEvent("entity2", "check1")
+	staging.Entity.Namespace = "staging"
+	staging.Check.Status = 0
+	assert.NoError(sendMessage(staging))
+	assert.Equal(4, posts)
+	assert.NoError(sendMessage(staging))
+	assert.Equal(4, posts)
+}
+
+func TestClaimDelivery(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+
+	// Handlers running at the same time each have their own store, and only
+	// one of them gets to deliver the notification.
+	const handlers = 10
+	var wg sync.WaitGroup
+	claims := make(chan bool, handlers)
+	for i := 0; i < handlers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := claimDelivery(&fileStateStore{path: path}, event)
+			assert.NoError(err)
+			claims <- claimed
+		}()
+	}
+	wg.Wait()
+	close(claims)
+	claimed := 0
+	for c := range claims {
+		if c {
+			claimed++
+		}
+	}
+	assert.Equal(1, claimed)
+
+	// A released claim can be taken again, and a recorded delivery can't
+	store := &fileStateStore{path: path}
+	require.NoError(t, releaseDelivery(store, event))
+	ok, err := claimDelivery(store, event)
+	require.NoError(t, err)
+	assert.True(ok)
+	require.NoError(t, recordNotification(store, event))
+	ok, err = claimDelivery(store, event)
+	require.NoError(t, err)
+	assert.False(ok)
+
+	// A claim left by a handler that died expires
+	event.Check.Status = 0
+	ok, _ = claimDelivery(store, event)
+	assert.True(ok)
+	ok, _ = claimDelivery(store, event)
+	assert.False(ok)
+	now = func() time.Time { return time.Now().Add(inFlightTimeout) }
+	defer func() { now = time.Now }()
+	ok, _ = claimDelivery(store, event)
+	assert.True(ok)
+}
+
+func TestMentionWindow(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "messages.jsonl")
+	config.slackwebHookURL = ""
+	config.slackChannel = "#ops"
+	config.slackDescriptionTemplate = "{{ .Check.Output }}"
+	config.slackAlertWarning = true
+	config.stateFile = filepath.Join(dir, "state.json")
+	config.mentionWindow = "30m"
+	config.outputFile = path
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	defer func() {
+		config.slackAlertWarning = false
+		config.stateFile = ""
+		config.mentionWindow = ""
+		config.outputFile = ""
+		now = time.Now
+	}()
+
+	mentions := func() []string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var texts []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var msg slack.WebhookMessage
+			require.NoError(t, json.Unmarshal([]byte(line), &msg))
+			texts = append(texts, msg.Text)
+		}
+		return texts
+	}
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 1
+	assert.NoError(sendMessage(event))
+
+	// inside the window, another warning is sent without the mention
+	clock = clock.Add(10 * time.Minute)
+	other := corev2.FixtureEvent("entity2", "check2")
+	other.Check.Status = 1
+	assert.NoError(sendMessage(other))
+
+	// outside the window, the mention is sent again
+	clock = clock.Add(25 * time.Minute)
+	assert.NoError(sendMessage(other))
+
+	assert.Equal([]string{"<!channel>", "", "<!channel>"}, mentions())
+
+	// Each mention has its own window: a critical within the window of the
+	// @channel above only mentions the on-call user
+	config.slackAlertCritical = true
+	config.criticalMentions = []string{"U0123ABCD"}
+	defer func() {
+		config.slackAlertCritical = false
+		config.criticalMentions = nil
+	}()
+	clock = clock.Add(5 * time.Minute)
+	critical := corev2.FixtureEvent("entity3", "check3")
+	critical.Check.Status = 2
+	assert.NoError(sendMessage(critical))
+	clock = clock.Add(5 * time.Minute)
+	critical.Check.Name = "check4"
+	assert.NoError(sendMessage(critical))
+	assert.Equal([]string{"<@U0123ABCD>", ""}, mentions()[3:])
+}
+
+func TestSinceLastNotification(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	config.slackwebHookURL = ""
+	config.outputFile = path
+	config.stateFile = filepath.Join(t.TempDir(), "state.json")
+	config.slackDescriptionTemplate = "last notified: {{ .SinceLastNotification }}"
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	defer func() {
+		config.outputFile = ""
+		config.stateFile = ""
+		now = time.Now
+	}()
+
+	texts := func() []string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var texts []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var msg slack.WebhookMessage
+			require.NoError(t, json.Unmarshal([]byte(line), &msg))
+			texts = append(texts, msg.Attachments[0].Text)
+		}
+		return texts
+	}
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+	assert.NoError(sendMessage(event))
+	clock = clock.Add(90 * time.Minute)
+	assert.NoError(sendMessage(event))
+
+	assert.Equal([]string{"last notified: first time", "last notified: 1h30m0s"}, texts())
+}
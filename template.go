@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	corev2 "github.com/sensu/core/v2"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	// Embed the time zone database so --timestamp-timezone works on hosts
+	// without one
+	_ "time/tzdata"
+)
+
+const defaultTimestampFormat = "2006-01-02 15:04:05 MST"
+
+// evalTemplate renders a description template. It provides the same
+// functions as the plugin SDK's templates.EvalTemplate, plus LocalTime and
+// statusTrend.
+func evalTemplate(name, text string, data interface{}) (string, error) {
+	if len(text) == 0 {
+		return "", fmt.Errorf("must pass in template")
+	}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"UnixTime":      func(i int64) time.Time { return time.Unix(i, 0) },
+		"UUIDFromBytes": uuid.FromBytes,
+		"Hostname":      os.Hostname,
+		"toJSON":        toJSON,
+		"LocalTime":     localTime,
+		"statusTrend":   statusTrend,
+	}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("Error building template: %s", err)
+	}
+	if err := addTemplatePartials(tmpl); err != nil {
+		return "", fmt.Errorf("Error building template: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("Error executing template: %s", err)
+	}
+	return buf.String(), nil
+}
+
+// trendLevels are the characters statusTrend draws each status severity with,
+// from OK to unknown.
+var trendLevels = []rune("▁▃▅▇")
+
+// statusTrend draws the check's status history as a row of block
+// characters, oldest first, rising with the severity of each status. It
+// accepts the template context or an event, so that {{ statusTrend . }} works
+// in any template. A check without history gives an empty string.
+func statusTrend(data interface{}) (string, error) {
+	var event *corev2.Event
+	switch v := data.(type) {
+	case templateContext:
+		event = v.Event
+	case *corev2.Event:
+		event = v
+	default:
+		return "", fmt.Errorf("statusTrend needs an event, got %T", data)
+	}
+	if event == nil || event.Check == nil {
+		return "", nil
+	}
+	trend := make([]rune, 0, len(event.Check.History))
+	for _, entry := range event.Check.History {
+		trend = append(trend, trendLevels[statusSeverity(entry.Status)])
+	}
+	return string(trend), nil
+}
+
+// addTemplatePartials parses the configured partials into the template's
+// set, so that it can include them by name.
+func addTemplatePartials(tmpl *template.Template) error {
+	names := make([]string, 0, len(config.templatePartials))
+	for name := range config.templatePartials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		text := config.templatePartials[name]
+		if strings.HasPrefix(text, "@") {
+			data, err := os.ReadFile(strings.TrimPrefix(text, "@"))
+			if err != nil {
+				return fmt.Errorf("reading partial %q: %v", name, err)
+			}
+			text = string(data)
+		}
+		if _, err := tmpl.New(name).Parse(text); err != nil {
+			return fmt.Errorf("partial %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func toJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// localTime formats a Unix timestamp in the configured time zone and format,
+// defaulting to UTC.
+func localTime(timestamp int64) (string, error) {
+	loc := time.UTC
+	if len(config.timestampTimezone) > 0 {
+		var err error
+		if loc, err = time.LoadLocation(config.timestampTimezone); err != nil {
+			return "", err
+		}
+	}
+	format := config.timestampFormat
+	if len(format) == 0 {
+		format = defaultTimestampFormat
+	}
+	return time.Unix(timestamp, 0).In(loc).Format(format), nil
+}
+
+// templatePresets are the built-in description templates, selected with
+// --template-preset when no description template is given.
+var templatePresets = map[string]string{
+	"minimal":  `{{ if eq .Check.Status 0 }}:white_check_mark: OK{{ else if eq .Check.Status 1 }}:warning: WARNING{{ else if eq .Check.Status 2 }}:warning: CRITICAL{{ else }}:warning: UNKNOWN{{ end }}: {{ .Check.Name }} on {{ .Entity.Name }}`,
+	"standard": defaultTemplate,
+	"verbose":  defaultTemplate + `\n*Occurrences:* {{ .Check.Occurrences }}\n*State change:* {{ .Check.TotalStateChange }}%\n*Namespace:* {{ .Entity.Namespace }}\n*Subscriptions:* {{ range $i, $s := .Check.Subscriptions }}{{ if $i }}, {{ end }}{{ $s }}{{ end }}`,
+}
+
+// baseDescriptionTemplate returns the configured description template, or the
+// selected preset if the description template was left at its default.
+func baseDescriptionTemplate() string {
+	if config.slackDescriptionTemplate != defaultTemplate {
+		return config.slackDescriptionTemplate
+	}
+	if preset, ok := templatePresets[config.templatePreset]; ok {
+		return preset
+	}
+	return config.slackDescriptionTemplate
+}
+
+// templateContext is the data the description template is rendered with. It
+// embeds the event so templates can keep using .Check, .Entity and so on, and
+// adds the handler and pipeline that are processing the event, the handler's
+// version, and how long ago the last notification for it was sent.
+type templateContext struct {
+	*corev2.Event
+	Handler               string
+	Pipeline              string
+	Version               string
+	SinceLastNotification string
+}
+
+// sinceLastNotified is the SinceLastNotification of the event being handled.
+// sendMessage reads it from the state file once, before any template is
+// rendered.
+var sinceLastNotified = "first time"
+
+func newTemplateContext(event *corev2.Event) templateContext {
+	return templateContext{
+		Event:                 event,
+		Handler:               config.PluginConfig.Name,
+		Pipeline:              eventPipeline(event),
+		Version:               handlerVersion(),
+		SinceLastNotification: sinceLastNotified,
+	}
+}
+
+// eventPipeline returns the name of the first pipeline referenced by the
+// event, or an empty string if the event carries no pipeline references.
+func eventPipeline(event *corev2.Event) string {
+	for _, ref := range event.Pipelines {
+		if ref != nil && len(ref.Name) > 0 {
+			return ref.Name
+		}
+	}
+	return ""
+}
+
+// handlerVersion returns the version the handler was built as, or "dev" for
+// builds that didn't set one.
+func handlerVersion() string {
+	if len(version) == 0 {
+		return "dev"
+	}
+	return version
+}
+
+// templateFooter returns the footer rendered from the footer template, if one
+// is configured.
+func templateFooter(event *corev2.Event) string {
+	if len(config.footerTemplate) == 0 {
+		return ""
+	}
+	footer, err := evalTemplate("footer", config.footerTemplate, newTemplateContext(event))
+	if err != nil {
+		logf("%s: Error processing footer template: %s\n", config.PluginConfig.Name, err)
+		return ""
+	}
+	return strings.TrimSpace(footer)
+}
+
+// handlerContextFooter returns the attachment footer naming the handler and,
+// when known, the pipeline that sent the message.
+func handlerContextFooter(event *corev2.Event) string {
+	footer := "Sent by " + config.PluginConfig.Name
+	if pipeline := eventPipeline(event); len(pipeline) > 0 {
+		footer += " via pipeline " + pipeline
+	}
+	return footer
+}
+
+// selectDescriptionTemplate returns the description template to render for the
+// event. If the event selects a template profile by label or annotation, that
+// profile's template is used; otherwise the configured description template or
+// preset.
+func selectDescriptionTemplate(event *corev2.Event) string {
+	name := eventMetadataValue(event, config.templateProfileLabel)
+	if len(name) == 0 {
+		return baseDescriptionTemplate()
+	}
+	profile, ok := config.templateProfiles[name]
+	if !ok {
+		logf("%s: Unknown template profile %q, using the default template\n", config.PluginConfig.Name, name)
+		return baseDescriptionTemplate()
+	}
+	if strings.HasPrefix(profile, "@") {
+		data, err := os.ReadFile(strings.TrimPrefix(profile, "@"))
+		if err != nil {
+			logf("%s: Error reading template profile %q: %s, using the default template\n", config.PluginConfig.Name, name, err)
+			return baseDescriptionTemplate()
+		}
+		return string(data)
+	}
+	return profile
+}
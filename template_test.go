@@ -0,0 +1,184 @@
+package main
+
+import (
+	corev2 "github.com/sensu/core/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescriptionTemplateProfiles(t *testing.T) {
+	assert := assert.New(t)
+	file := filepath.Join(t.TempDir(), "app.tmpl")
+	require.NoError(t, os.WriteFile(file, []byte("app: {{ .Check.Name }}"), 0600))
+
+	config.slackDescriptionTemplate = "default: {{ .Check.Name }}"
+	config.templateProfileLabel = "template_profile"
+	config.templateProfiles = map[string]string{
+		"infra": "infra: {{ .Entity.Name }}",
+		"app":   "@" + file,
+	}
+	defer func() {
+		config.templateProfiles = nil
+		config.templateProfileLabel = ""
+	}()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	assert.Equal("default: {{ .Check.Name }}", selectDescriptionTemplate(event))
+
+	event.Check.Labels = map[string]string{"template_profile": "infra"}
+	assert.Equal("infra: {{ .Entity.Name }}", selectDescriptionTemplate(event))
+	assert.Equal("infra: entity1", messageAttachment(event).Text)
+
+	event.Check.Labels = nil
+	event.Entity.Annotations = map[string]string{"template_profile": "app"}
+	assert.Equal("app: check1", messageAttachment(event).Text)
+
+	event.Entity.Annotations = map[string]string{"template_profile": "missing"}
+	assert.Equal("default: {{ .Check.Name }}", selectDescriptionTemplate(event))
+}
+
+func TestHandlerContext(t *testing.T) {
+	assert := assert.New(t)
+	config.slackDescriptionTemplate = "{{ .Check.Name }} from {{ .Handler }}{{ with .Pipeline }} in {{ . }}{{ end }}"
+	config.PluginConfig.Name = "sensu-slack-handler"
+	defer func() { config.handlerFooter = false }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	assert.Equal("check1 from sensu-slack-handler", messageAttachment(event).Text)
+	assert.Empty(messageAttachment(event).Footer)
+
+	config.handlerFooter = true
+	assert.Equal("Sent by sensu-slack-handler", messageAttachment(event).Footer)
+
+	event.Pipelines = []*corev2.ResourceReference{{Name: "incidents", Type: "Pipeline", APIVersion: "core/v2"}}
+	attachment := messageAttachment(event)
+	assert.Equal("check1 from sensu-slack-handler in incidents", attachment.Text)
+	assert.Equal("Sent by sensu-slack-handler via pipeline incidents", attachment.Footer)
+}
+
+func TestTemplatePresets(t *testing.T) {
+	assert := assert.New(t)
+	config.slackDescriptionTemplate = defaultTemplate
+	defer func() { config.templatePreset = "" }()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Status = 2
+	event.Check.Occurrences = 3
+	event.Check.Subscriptions = []string{"linux", "web"}
+
+	config.templatePreset = "minimal"
+	assert.Equal(":warning: CRITICAL: check1 on entity1", messageAttachment(event).Text)
+
+	config.templatePreset = "standard"
+	text := messageAttachment(event).Text
+	assert.Contains(text, "*CRITICAL* *<https://sensu.io|check1>* on entity1")
+	assert.NotContains(text, "Occurrences")
+
+	config.templatePreset = "verbose"
+	text = messageAttachment(event).Text
+	assert.Contains(text, "*CRITICAL* *<https://sensu.io|check1>* on entity1")
+	assert.Contains(text, "\n*Occurrences:* 3\n")
+	assert.Contains(text, "*Subscriptions:* linux, web")
+
+	config.slackDescriptionTemplate = "{{ .Check.Name }}"
+	assert.Equal("check1", messageAttachment(event).Text)
+}
+
+func TestLocalTime(t *testing.T) {
+	assert := assert.New(t)
+	defer func() {
+		config.timestampTimezone = ""
+		config.timestampFormat = ""
+	}()
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Timestamp = 1700000000
+	tmpl := "{{ LocalTime .Timestamp }}"
+	config.timestampFormat = "2006-01-02 15:04 MST"
+
+	config.timestampTimezone = "America/New_York"
+	out, err := evalTemplate("test", tmpl, event)
+	assert.NoError(err)
+	assert.Equal("2023-11-14 17:13 EST", out)
+
+	config.timestampTimezone = "Australia/Sydney"
+	out, err = evalTemplate("test", tmpl, event)
+	assert.NoError(err)
+	assert.Equal("2023-11-15 09:13 AEDT", out)
+
+	config.timestampTimezone = ""
+	out, err = evalTemplate("test", tmpl, event)
+	assert.NoError(err)
+	assert.Equal("2023-11-14 22:13 UTC", out)
+}
+
+func TestTemplatePartials(t *testing.T) {
+	assert := assert.New(t)
+	config.accessible = false
+	defer func() { config.templatePartials = nil }()
+
+	path := filepath.Join(t.TempDir(), "footer.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte("-- {{ .Entity.Name }}"), 0644))
+	config.templatePartials = map[string]string{
+		"header": "[{{ .Check.Name }}]",
+		"footer": "@" + path,
+	}
+	config.slackDescriptionTemplate = `{{ template "header" . }} {{ .Check.Output }} {{ template "footer" . }}`
+
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.Output = "disk full"
+	assert.Equal("[check1] disk full -- entity1", renderDescription(event))
+
+	config.slackDescriptionTemplate = `{{ template "missing" . }}`
+	_, err := evalTemplate("description", config.slackDescriptionTemplate, event)
+	assert.Error(err)
+}
+
+func TestFooterTemplate(t *testing.T) {
+	assert := assert.New(t)
+	config.slackDescriptionTemplate = "{{ .Check.Name }}"
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Entity.Namespace = "production"
+
+	assert.Empty(messageAttachment(event).Footer)
+
+	config.footerTemplate = "{{ .Entity.Namespace }} | sensu-slack-handler {{ .Version }}"
+	config.footerIconURL = "https://www.sensu.io/img/sensu-logo.png"
+	defer func() {
+		config.footerTemplate = ""
+		config.footerIconURL = ""
+		version = ""
+	}()
+	attachment := messageAttachment(event)
+	assert.Equal("production | sensu-slack-handler dev", attachment.Footer)
+	assert.Equal("https://www.sensu.io/img/sensu-logo.png", attachment.FooterIcon)
+
+	version = "1.5.0"
+	assert.Equal("production | sensu-slack-handler 1.5.0", messageAttachment(event).Footer)
+}
+
+func TestStatusTrend(t *testing.T) {
+	assert := assert.New(t)
+	event := corev2.FixtureEvent("entity1", "check1")
+	event.Check.History = nil
+
+	trend, err := statusTrend(event)
+	assert.NoError(err)
+	assert.Equal("", trend)
+
+	for _, status := range []uint32{0, 0, 1, 2, 2, 3, 127, 0} {
+		event.Check.History = append(event.Check.History, corev2.CheckHistory{Status: status})
+	}
+	trend, err = statusTrend(event)
+	assert.NoError(err)
+	assert.Equal("▁▁▃▅▅▇▇▁", trend)
+
+	config.slackDescriptionTemplate = "{{ .Check.Name }} {{ statusTrend . }}"
+	assert.Equal("check1 ▁▁▃▅▅▇▇▁", messageAttachment(event).Text)
+
+	_, err = statusTrend("check1")
+	assert.Error(err)
+}